@@ -1,6 +1,7 @@
 package rtsp
 
 import (
+	"context"
 	"net/url"
 	"strings"
 )
@@ -10,23 +11,262 @@ type Auth interface {
 	Header(method, uri string) string
 }
 
+// CredentialsProvider returns credentials for authentication.
+// Called on each 401 Unauthorized and 407 Proxy Authentication Required
+// response, so credentials can be rotated without reconnection
+type CredentialsProvider interface {
+	// Credentials returns login and password for the server, or for the proxy
+	// if proxy is true. u is the server or the proxy URL without credentials,
+	// realm is defined with the server challenge
+	Credentials(ctx context.Context, u *url.URL, realm string, proxy bool) (login, password string, err error)
+}
+
+// AuthBody is the optional Auth interface for schemes
+// protecting the request body, like digest with qop=auth-int
+type AuthBody interface {
+	// HeaderBody returns value for Authorization header
+	// for the request with body.
+	HeaderBody(method, uri string, body []byte) string
+}
+
+// NewAuth makes authentication with value of WWW-Authenticate header
+// and credentials from URL.
+// Returns nil if URL has no credentials or no challenges are supported
 func NewAuth(u *url.URL, header string) Auth {
+	return NewAuthChallenges(u, []string{header})
+}
+
+// NewAuthChallenges makes authentication with the strongest supported challenge
+// from values of WWW-Authenticate headers and credentials from URL.
+// Returns nil if URL has no credentials or no challenges are supported
+func NewAuthChallenges(u *url.URL, challenges []string) Auth {
 	login := u.User.Username()
 	password, ok := u.User.Password()
 	if !ok {
 		return nil
 	}
 
-	header = strings.TrimSpace(header)
+	if c := selectAuthChallenge(challenges, true); c != nil {
+		return c.newAuth(login, password)
+	}
+
+	return nil
+}
 
-	if method, params, ok := strings.Cut(header, " "); ok {
-		switch strings.ToLower(method) {
-		case "digest":
-			return NewAuthDigest(login, password, params)
-		case "basic":
-			return NewAuthBasic(login, password, params)
+// selectAuthChallenge returns the strongest supported challenge.
+// Basic scheme is skipped if allowBasic is false.
+// Returns nil if no challenges are supported
+func selectAuthChallenge(challenges []string, allowBasic bool) *authChallenge {
+	var best *authChallenge
+	bestStrength := 0
+
+	for _, c := range parseAuthChallenges(challenges) {
+		if c.scheme == "basic" && !allowBasic {
+			continue
+		}
+
+		if strength := c.strength(); strength > bestStrength {
+			best = c
+			bestStrength = strength
 		}
 	}
 
+	return best
+}
+
+// renewAuth applies the stale challenge to the digest authentication.
+// Challenge should have the same algorithm.
+// Returns false if auth is not digest or no stale challenges
+func renewAuth(auth Auth, challenges []string) bool {
+	a, ok := auth.(*AuthDigest)
+	if !ok {
+		return false
+	}
+
+	for _, c := range parseAuthChallenges(challenges) {
+		if c.scheme != "digest" {
+			continue
+		}
+
+		algorithm := parseAuthParams(c.params)["algorithm"]
+		if !strings.EqualFold(algorithm, a.algorithm) {
+			continue
+		}
+
+		if a.renew(c.params) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// authChallenge is the authentication scheme with parameters
+// https://datatracker.ietf.org/doc/html/rfc7235#section-2.1
+type authChallenge struct {
+	// scheme is the scheme name in lower case
+	scheme string
+	// params is the comma-separated list of auth-param or token68
+	params string
+}
+
+// strength returns the challenge priority. 0 if scheme is not supported
+func (c *authChallenge) strength() int {
+	switch c.scheme {
+	case "basic":
+		return 1
+	case "digest":
+		algorithm := parseAuthParams(c.params)["algorithm"]
+		newHash, _ := digestAlgorithm(algorithm)
+		if newHash == nil {
+			return 0
+		}
+		return 1 + newHash().Size()
+	}
+
+	return 0
+}
+
+// realm returns the protection space defined by the server
+func (c *authChallenge) realm() string {
+	return parseAuthParams(c.params)["realm"]
+}
+
+// newAuth makes authentication for the challenge
+func (c *authChallenge) newAuth(login, password string) Auth {
+	switch c.scheme {
+	case "digest":
+		return NewAuthDigest(login, password, c.params)
+	case "basic":
+		return NewAuthBasic(login, password, c.params)
+	}
+
 	return nil
 }
+
+// parseAuthChallenges parses values of WWW-Authenticate headers.
+// Each value may contain several comma-separated challenges
+func parseAuthChallenges(values []string) []*authChallenge {
+	var result []*authChallenge
+
+	for _, value := range values {
+		var current *authChallenge
+
+		for _, item := range splitAuthItems(value) {
+			// new challenge begins with scheme name
+			// followed with space or end of item
+			name, rest, _ := strings.Cut(item, " ")
+			rest = strings.TrimSpace(rest)
+			if !strings.Contains(name, "=") && !strings.HasPrefix(rest, "=") {
+				current = &authChallenge{
+					scheme: strings.ToLower(name),
+					params: rest,
+				}
+				result = append(result, current)
+				continue
+			}
+
+			if current == nil {
+				continue
+			}
+
+			if current.params == "" {
+				current.params = item
+			} else {
+				current.params += ", " + item
+			}
+		}
+	}
+
+	return result
+}
+
+// splitAuthItems splits value with commas out of quoted strings.
+// Empty items are skipped
+func splitAuthItems(value string) []string {
+	var result []string
+
+	quoted := false
+	begin := 0
+
+	for i := 0; i <= len(value); i++ {
+		if i < len(value) {
+			switch value[i] {
+			case '\\':
+				if quoted && i+1 < len(value) {
+					i++
+				}
+				continue
+			case '"':
+				quoted = !quoted
+				continue
+			case ',':
+				if quoted {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+
+		if item := strings.TrimSpace(value[begin:i]); item != "" {
+			result = append(result, item)
+		}
+		begin = i + 1
+	}
+
+	return result
+}
+
+// parseAuthParams parses comma-separated list of auth-param.
+// Names are in lower case, quoted values are unquoted.
+// https://datatracker.ietf.org/doc/html/rfc7235#section-2.1
+func parseAuthParams(s string) map[string]string {
+	params := make(map[string]string)
+
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if s == "" {
+			return params
+		}
+
+		name, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			return params
+		}
+
+		name = strings.ToLower(strings.TrimSpace(name))
+		rest = strings.TrimLeft(rest, " \t")
+
+		var value string
+		value, s = readAuthParamValue(rest)
+		params[name] = value
+	}
+}
+
+// readAuthParamValue reads token or quoted-string.
+// Returns the value and the rest of string
+func readAuthParamValue(s string) (string, string) {
+	if !strings.HasPrefix(s, "\"") {
+		value, rest, _ := strings.Cut(s, ",")
+		return strings.TrimSpace(value), rest
+	}
+
+	var b strings.Builder
+
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), s[i+1:]
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+
+	return b.String(), ""
+}
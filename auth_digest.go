@@ -2,7 +2,11 @@ package rtsp
 
 import (
 	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"hash"
 	"strings"
 )
 
@@ -14,6 +18,24 @@ type AuthDigest struct {
 	nonce    string
 	opaque   string
 	realm    string
+
+	// algorithm is the value from the challenge, empty for legacy RFC 2069
+	algorithm string
+	newHash   func() hash.Hash
+	session   bool
+	// qop is the selected quality of protection, empty for legacy RFC 2069
+	qop      string
+	userhash bool
+	nc       uint32
+
+	// sessionKey is HA1 for the session algorithms,
+	// computed once per nonce with the first cnonce
+	// https://datatracker.ietf.org/doc/html/rfc7616#section-3.4.2
+	sessionKey    string
+	sessionCnonce string
+
+	// newCnonce returns client nonce. Defined in tests
+	newCnonce func() string
 }
 
 func NewAuthDigest(login, password, header string) *AuthDigest {
@@ -22,58 +44,166 @@ func NewAuthDigest(login, password, header string) *AuthDigest {
 		password: password,
 	}
 
-	h := strings.Split(header, ",")
+	a.challenge(parseAuthParams(header))
+
+	return a
+}
+
+// challenge applies parameters of the server challenge
+func (a *AuthDigest) challenge(params map[string]string) {
+	a.nonce = params["nonce"]
+	a.opaque = params["opaque"]
+	a.realm = params["realm"]
+	a.nc = 0
+	a.sessionKey = ""
+	a.sessionCnonce = ""
 
-	for _, v := range h {
-		v = strings.TrimSpace(v)
+	a.algorithm = params["algorithm"]
+	a.newHash, a.session = digestAlgorithm(a.algorithm)
 
-		if name, value, ok := strings.Cut(v, "="); ok {
-			switch name {
-			case "nonce":
-				a.nonce = strings.Trim(value, "\"")
-			case "opaque":
-				a.opaque = strings.Trim(value, "\"")
-			case "realm":
-				a.realm = strings.Trim(value, "\"")
-			}
+	a.qop = ""
+	for _, qop := range strings.Split(params["qop"], ",") {
+		qop = strings.ToLower(strings.TrimSpace(qop))
+		if qop == "auth" {
+			a.qop = qop
+			break
+		}
+		if qop == "auth-int" {
+			a.qop = qop
 		}
 	}
 
-	return a
+	a.userhash = strings.EqualFold(params["userhash"], "true")
+}
+
+// digestAlgorithm returns hash function for the algorithm
+// and true for the session variant.
+// Returns nil hash function if algorithm is not supported
+func digestAlgorithm(algorithm string) (func() hash.Hash, bool) {
+	switch strings.ToUpper(algorithm) {
+	case "", "MD5":
+		return md5.New, false
+	case "MD5-SESS":
+		return md5.New, true
+	case "SHA-256":
+		return sha256.New, false
+	case "SHA-256-SESS":
+		return sha256.New, true
+	default:
+		return nil, false
+	}
+}
+
+// renew applies the challenge with stale=true.
+// Keeps credentials and replaces nonce.
+// Returns false if the challenge is not stale
+func (a *AuthDigest) renew(header string) bool {
+	params := parseAuthParams(header)
+	if !strings.EqualFold(params["stale"], "true") {
+		return false
+	}
+
+	a.challenge(params)
+
+	return true
 }
 
 func (a *AuthDigest) Header(method, uri string) string {
-	if a.nonce == "" {
+	return a.HeaderBody(method, uri, nil)
+}
+
+func (a *AuthDigest) HeaderBody(method, uri string, body []byte) string {
+	if a.nonce == "" || a.newHash == nil {
 		return ""
 	}
 
-	ha1 := md5.New()
-	fmt.Fprintf(ha1, "%s:%s:%s", a.login, a.realm, a.password)
-	sum1 := ha1.Sum(nil)
+	var cnonce, ha1 string
+	if a.session {
+		if a.sessionKey == "" {
+			a.sessionCnonce = a.cnonce()
+			a.sessionKey = a.hash(
+				a.hash(a.login+":"+a.realm+":"+a.password) + ":" + a.nonce + ":" + a.sessionCnonce,
+			)
+		}
+		cnonce, ha1 = a.sessionCnonce, a.sessionKey
+	} else {
+		if a.qop != "" {
+			cnonce = a.cnonce()
+		}
+		ha1 = a.hash(a.login + ":" + a.realm + ":" + a.password)
+	}
+
+	ha2 := a.hash(method + ":" + uri)
+	if a.qop == "auth-int" {
+		ha2 = a.hash(method + ":" + uri + ":" + a.hash(string(body)))
+	}
 
-	ha2 := md5.New()
-	fmt.Fprintf(ha2, "%s:%s", method, uri)
-	sum2 := ha2.Sum(nil)
+	var resp, nc string
+	if a.qop != "" {
+		a.nc += 1
+		nc = fmt.Sprintf("%08x", a.nc)
+		resp = a.hash(ha1 + ":" + a.nonce + ":" + nc + ":" + cnonce + ":" + a.qop + ":" + ha2)
+	} else {
+		resp = a.hash(ha1 + ":" + a.nonce + ":" + ha2)
+	}
 
-	ha3 := md5.New()
-	fmt.Fprintf(ha3, "%x:%s:%x", sum1, a.nonce, sum2)
-	resp := ha3.Sum(nil)
+	username := a.login
+	if a.userhash {
+		username = a.hash(a.login + ":" + a.realm)
+	}
 
 	var sb strings.Builder
 
 	_, _ = fmt.Fprintf(
 		&sb,
-		`Digest username="%s", uri="%s", realm="%s", nonce="%s", response="%x"`,
-		a.login,
+		`Digest username="%s", uri="%s", realm="%s", nonce="%s", response="%s"`,
+		username,
 		uri,
 		a.realm,
 		a.nonce,
 		resp,
 	)
 
+	if a.algorithm != "" {
+		_, _ = fmt.Fprintf(&sb, `, algorithm=%s`, a.algorithm)
+	}
+
+	if a.qop != "" {
+		_, _ = fmt.Fprintf(&sb, `, qop=%s, nc=%s, cnonce="%s"`, a.qop, nc, cnonce)
+	} else if cnonce != "" {
+		_, _ = fmt.Fprintf(&sb, `, cnonce="%s"`, cnonce)
+	}
+
 	if a.opaque != "" {
 		_, _ = fmt.Fprintf(&sb, `, opaque="%s"`, a.opaque)
 	}
 
+	if a.userhash {
+		sb.WriteString(`, userhash=true`)
+	}
+
 	return sb.String()
 }
+
+// cnonce returns new client nonce
+func (a *AuthDigest) cnonce() string {
+	if a.newCnonce != nil {
+		return a.newCnonce()
+	}
+
+	return newDigestCnonce()
+}
+
+// hash returns hex-encoded digest of the value
+func (a *AuthDigest) hash(value string) string {
+	h := a.newHash()
+	h.Write([]byte(value))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// newDigestCnonce returns random client nonce
+func newDigestCnonce() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
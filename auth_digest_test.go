@@ -1,6 +1,8 @@
 package rtsp
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"net/url"
 	"strings"
 	"testing"
@@ -45,3 +47,99 @@ func TestAuthDigest_Header(t *testing.T) {
 		auth.Header("DESCRIBE", uri),
 	)
 }
+
+// Examples from RFC 7616 Section 3.9.1
+func TestAuthDigest_RFC7616(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		response  string
+	}{
+		{"MD5", "8ca523f5e9506fed4657c9700eebdbec"},
+		{"SHA-256", "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"},
+	}
+
+	for _, test := range tests {
+		authItems := []string{
+			`realm="http-auth@example.org"`,
+			`qop="auth, auth-int"`,
+			`algorithm=` + test.algorithm,
+			`nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v"`,
+			`opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`,
+		}
+
+		auth := NewAuthDigest("Mufasa", "Circle of Life", strings.Join(authItems, ", "))
+		auth.newCnonce = func() string {
+			return "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ"
+		}
+
+		expectedItems := []string{
+			`Digest username="Mufasa"`,
+			`uri="/dir/index.html"`,
+			`realm="http-auth@example.org"`,
+			`nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v"`,
+			`response="` + test.response + `"`,
+			`algorithm=` + test.algorithm,
+			`qop=auth`,
+			`nc=00000001`,
+			`cnonce="f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ"`,
+			`opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`,
+		}
+
+		assert.Equal(
+			t,
+			strings.Join(expectedItems, ", "),
+			auth.Header("GET", "/dir/index.html"),
+			test.algorithm,
+		)
+	}
+}
+
+func TestAuthDigest_Qop(t *testing.T) {
+	assert := assert.New(t)
+
+	auth := NewAuthDigest("test", "pass", `realm="test", nonce="1234", qop="auth-int", algorithm=SHA-256-sess, userhash=true`)
+	cnonces := []string{"abcd", "efgh", "ijkl"}
+	auth.newCnonce = func() string {
+		cnonce := cnonces[0]
+		cnonces = cnonces[1:]
+		return cnonce
+	}
+
+	sha := func(s string) string {
+		h := sha256.Sum256([]byte(s))
+		return hex.EncodeToString(h[:])
+	}
+
+	body := []byte("v=0\r\n")
+	ha1 := sha(sha("test:test:pass") + ":1234:abcd")
+	ha2 := sha("ANNOUNCE:rtsp://127.0.0.1/live:" + sha(string(body)))
+
+	for _, nc := range []string{"00000001", "00000002"} {
+		params := parseAuthParams(
+			strings.TrimPrefix(auth.HeaderBody("ANNOUNCE", "rtsp://127.0.0.1/live", body), "Digest "),
+		)
+
+		assert.Equal(sha("test:test"), params["username"])
+		assert.Equal("true", params["userhash"])
+		assert.Equal("auth-int", params["qop"])
+		assert.Equal(nc, params["nc"])
+		// session key is computed once with the first cnonce
+		assert.Equal("abcd", params["cnonce"])
+		assert.Equal(sha(ha1+":1234:"+nc+":abcd:auth-int:"+ha2), params["response"])
+	}
+
+	// stale nonce resets the nonce count
+	assert.False(auth.renew(`realm="test", nonce="5678", qop="auth"`))
+	assert.True(auth.renew(`realm="test", nonce="5678", qop="auth", algorithm=SHA-256-sess, stale=true`))
+
+	params := parseAuthParams(strings.TrimPrefix(auth.Header("PLAY", "rtsp://127.0.0.1/live"), "Digest "))
+	assert.Equal("5678", params["nonce"])
+	assert.Equal("auth", params["qop"])
+	assert.Equal("00000001", params["nc"])
+
+	// new session key for the new nonce
+	assert.Equal("efgh", params["cnonce"])
+	ha1 = sha(sha("test:test:pass") + ":5678:efgh")
+	ha2 = sha("PLAY:rtsp://127.0.0.1/live")
+	assert.Equal(sha(ha1+":5678:00000001:efgh:auth:"+ha2), params["response"])
+}
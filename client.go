@@ -3,11 +3,15 @@ package rtsp
 import (
 	"bufio"
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -16,7 +20,10 @@ const (
 	MethodDescribe     = "DESCRIBE"
 	MethodGetParameter = "GET_PARAMETER"
 	MethodOptions      = "OPTIONS"
+	MethodPause        = "PAUSE"
 	MethodPlay         = "PLAY"
+	MethodRedirect     = "REDIRECT"
+	MethodSetParameter = "SET_PARAMETER"
 	MethodSetup        = "SETUP"
 	MethodTeardown     = "TEARDOWN"
 )
@@ -24,6 +31,11 @@ const (
 var (
 	ErrClientClosed    = fmt.Errorf("client closed")
 	ErrResponseTimeout = fmt.Errorf("response timeout")
+	ErrRedirect        = fmt.Errorf("redirect")
+
+	ErrTooManyRedirects = fmt.Errorf("too many redirects")
+
+	ErrKeepAliveNotSupported = fmt.Errorf("keep-alive is not supported")
 )
 
 // Real Time Streaming Protocol (RTSP)
@@ -35,42 +47,192 @@ type Client struct {
 	ConnectTimeout time.Duration
 	RequestTimeout time.Duration
 
+	// MaxRedirects is the number of 3xx redirects to follow on OPTIONS
+	// and DESCRIBE, and REDIRECT requests from the server during playback.
+	// Default is 5. Negative value disables redirects
+	MaxRedirects int
+
+	// BasicAuthTLSOnly disables Basic authentication for plain text connections.
+	// Basic is used only with rtsps:// scheme, other schemes are selected
+	// from WWW-Authenticate challenges
+	BasicAuthTLSOnly bool
+
+	// Credentials returns login and password on authentication request.
+	// If not defined, credentials are taken from URL for the server
+	// and from Proxy URL for the proxy
+	Credentials CredentialsProvider
+
+	// Proxy is the RTSP proxy to connect.
+	// Requests are sent to the proxy with the absolute server URL
+	Proxy *url.URL
+
+	// TLSConfig is the TLS configuration for rtsps:// scheme.
+	// ServerName for SNI is defined from the URL if not set.
+	// Use VerifyConnection with PinPublicKeys for certificate pinning
+	TLSConfig *tls.Config
+
+	// HTTPTunnel enables RTSP-over-HTTP tunneling.
+	// Media is delivered with interleaved frames in the tunnel, UseTCP is implied.
+	// If URL port is not defined, tunnel is connected to port 80,
+	// or 443 for rtsps:// scheme
+	HTTPTunnel bool
+
+	// UDPConfig is the socket options for UDP transport
+	UDPConfig UDPConfig
+	// TCPConfig is the socket options for the RTSP connection.
+	// Applied to the interleaved transport and the HTTP tunnel
+	TCPConfig TCPConfig
+
+	// HolePunch sends dummy RTP and RTCP packets to the server after SETUP
+	// to open NAT mapping for UDP transport
+	HolePunch bool
+	// HolePunchSchedule is the delays from SETUP to send dummy packets.
+	// Default is 0, 200ms, 1s and 3s
+	HolePunchSchedule []time.Duration
+
+	// TCPFallback switches to the interleaved TCP transport if UDP does not work:
+	// server responds 461 Unsupported Transport to SETUP,
+	// or no RTP packets received in FallbackTimeout after PLAY.
+	// Client stays on TCP after fallback
+	TCPFallback bool
+	// FallbackTimeout is the time to wait for the first RTP packet over UDP.
+	// Default is 5 seconds
+	FallbackTimeout time.Duration
+
+	// Multicast requests media delivery to the multicast group.
+	// Ignored if UseTCP or HTTPTunnel is set
+	Multicast bool
+	// MulticastInterface is the network interface to join multicast groups.
+	// System default interface is used if nil
+	MulticastInterface *net.Interface
+
+	// EndOnBye stops Play with ErrEndOfStream when the server sends RTCP BYE
+	EndOnBye bool
+	// ReceiverReports sends RTCP receiver reports to the server.
+	// Some servers close the session without reports
+	ReceiverReports bool
+
+	// Scale is the play rate for PLAY requests. Not sent if 0
+	Scale Scale
+	// Speed is the delivery speed for PLAY requests. Not sent if 0
+	Speed Speed
+
+	// proxy is the address to connect defined with 305 Use Proxy
+	proxy *url.URL
+	// redirects is the number of followed redirects
+	redirects int
+
 	conn net.Conn
 	br   *bufio.Reader
 	bw   *bufio.Writer
 
-	cseq    int
-	session string
-	auth    Auth
-	sdp     []*SdpItem
+	// lock serializes requests from Play loop and user calls
+	lock sync.Mutex
+	// wlock serializes writes of requests and interleaved packets
+	wlock sync.Mutex
+
+	cseq           int
+	session        string
+	sessionTimeout time.Duration
+	auth           Auth
+	proxyAuth      Auth
+	sdp            []*SdpItem
+	playing        bool
+
+	// public is the set of methods supported by the server
+	public map[string]struct{}
+	// unsupported is the set of methods rejected by the server
+	unsupported map[string]struct{}
+
+	playRange Range
+	rtpInfo   []*RTPInfo
+	scale     Scale
+	speed     Speed
 
 	transport Transport
+	// transports is the negotiated transport for each media
+	transports map[int]*TransportHeader
+	// setups is the list of successful SETUP requests to replay on fallback
+	setups []setupRequest
+	// fallback is set when UDP transport is replaced with TCP
+	fallback bool
+
+	// demux is set when interleaved transport reads the connection.
+	// Responses are delivered to the responses channel
+	demux     bool
+	responses chan *Response
+}
+
+const (
+	defaultTimeout      = 5 * time.Second
+	defaultMaxRedirects = 5
+	// defaultSessionTimeout is the session timeout if server does not define it
+	defaultSessionTimeout = 60 * time.Second
+)
+
+// errNoMedia is returned by serve if no RTP packets received in FallbackTimeout
+var errNoMedia = fmt.Errorf("no media received")
+
+// clockRateSetter is the transport sending receiver reports
+type clockRateSetter interface {
+	SetClockRate(mediaID, clockRate int)
 }
 
-const defaultTimeout = 5 * time.Second
+// setupRequest is the SETUP request parameters
+type setupRequest struct {
+	mediaID int
+	control *url.URL
+	mode    string
+}
 
-func getSession(response *Response) string {
-	if session := response.Header.Get("Session"); session != "" {
-		session, _, _ = strings.Cut(session, ";")
-		return strings.TrimSpace(session)
+var defaultHolePunchSchedule = []time.Duration{
+	0,
+	200 * time.Millisecond,
+	time.Second,
+	3 * time.Second,
+}
+
+// getSession returns session identifier and timeout from the Session header.
+// Timeout is 0 if not defined
+func getSession(response *Response) (string, time.Duration) {
+	session := response.Header.Get("Session")
+	if session == "" {
+		return "", 0
 	}
 
-	return ""
+	session, params, _ := strings.Cut(session, ";")
+
+	var timeout time.Duration
+	for _, param := range strings.Split(params, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		if strings.ToLower(key) != "timeout" {
+			continue
+		}
+
+		if v, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && v > 0 {
+			timeout = time.Duration(v) * time.Second
+		}
+	}
+
+	return strings.TrimSpace(session), timeout
 }
 
 func (c *Client) do(ctx context.Context, request *Request) (response *Response, err error) {
-	cseq := c.cseq
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	conn := c.conn
 
-	done := make(chan error, 1)
+	done := make(chan struct{})
+	interrupt := make(chan struct{})
+	exit := make(chan error, 1)
 	defer func() {
-		select {
-		case err = <-done:
+		close(done)
+
+		if e := <-exit; e != nil {
 			// context is canceled. socket closed in goroutine.
-		default:
-			// func is finished. do nothing.
+			err = e
 		}
-
-		close(done)
 	}()
 
 	go func() {
@@ -81,40 +243,64 @@ func (c *Client) do(ctx context.Context, request *Request) (response *Response,
 		select {
 		case <-done:
 			// func is finished. do nothing
+			exit <- nil
 		case <-ctx.Done():
 			// context is canceled. close socket to break IO
-			done <- ctx.Err()
-			c.conn.Close()
+			close(interrupt)
+			conn.Close()
+			exit <- ctx.Err()
 		case <-timeout.C:
 			// timeout. close socket to break IO
-			done <- ErrResponseTimeout
-			c.conn.Close()
+			close(interrupt)
+			conn.Close()
+			exit <- ErrResponseTimeout
 		}
 	}()
 
+	// Limit authentication attempts for the server and the proxy.
+	// Nonce renewal is not counted as authentication attempt
+	authorized, proxyAuthorized := false, false
+	renewed, proxyRenewed := false, false
+
 	for {
-		if err = c.SendRequest(request); err != nil {
-			return nil, err
+		if c.demux {
+			// drop late responses
+			select {
+			case <-c.responses:
+			default:
+			}
 		}
 
-		response, err = ReadResponse(c.br)
-		if err != nil {
+		if err = c.SendRequest(request); err != nil {
 			return nil, err
 		}
 
-		err = response.ReadBody(c.br)
+		response, err = c.receive(interrupt)
 		if err != nil {
 			return nil, err
 		}
 
-		if response.StatusCode == http.StatusUnauthorized {
-			// Limit authentication attempts
-			if (c.cseq - cseq) > 1 {
-				break
+		if response.StatusCode == StatusUnauthorized || response.StatusCode == StatusProxyAuthRequired {
+			proxy := response.StatusCode == StatusProxyAuthRequired
+
+			auth, attempted, stale := &c.auth, &authorized, &renewed
+			challenges := response.Header.Values("www-authenticate")
+			if proxy {
+				auth, attempted, stale = &c.proxyAuth, &proxyAuthorized, &proxyRenewed
+				challenges = response.Header.Values("proxy-authenticate")
+			}
+
+			// Server rejects the nonce, but not credentials
+			if !*stale && renewAuth(*auth, challenges) {
+				*stale = true
+				continue
 			}
 
-			if h := response.Header.Get("www-authenticate"); h != "" {
-				c.auth = NewAuth(request.URL, h)
+			if !*attempted && len(challenges) != 0 {
+				*attempted = true
+				if *auth, err = c.authenticate(ctx, challenges, proxy); err != nil {
+					return nil, err
+				}
 				continue
 			}
 		}
@@ -122,22 +308,174 @@ func (c *Client) do(ctx context.Context, request *Request) (response *Response,
 		break
 	}
 
-	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%s", response.Status)
+	if response.StatusCode != StatusOK {
+		// response is returned to check the status code
+		return response, newStatusError(request, response)
 	}
 
 	return response, nil
 }
 
-// Start connectes to the RTSP server and get SDP
-func (c *Client) Start(ctx context.Context) error {
-	c.session = ""
+// receive returns response from the connection or from the interleaved transport
+func (c *Client) receive(interrupt <-chan struct{}) (*Response, error) {
+	if c.demux {
+		select {
+		case response := <-c.responses:
+			return response, nil
+		case <-interrupt:
+			return nil, net.ErrClosed
+		}
+	}
+
+	for {
+		response, err := c.readMessage(c.br)
+		if err != nil {
+			return nil, err
+		}
+
+		if response != nil {
+			return response, nil
+		}
+
+		// request from the server is handled. wait for response
+	}
+}
+
+// readMessage reads RTSP message from the connection.
+// Returns response or nil if server sent a request
+func (c *Client) readMessage(reader *bufio.Reader) (*Response, error) {
+	b, err := reader.Peek(5)
+	if err != nil {
+		return nil, err
+	}
+
+	if string(b) == "RTSP/" {
+		response, err := ReadResponse(reader)
+		if err != nil {
+			return nil, err
+		}
+
+		if err = response.ReadBody(reader); err != nil {
+			return nil, err
+		}
+
+		return response, nil
+	}
+
+	request, err := ReadRequest(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = request.ReadBody(reader); err != nil {
+		return nil, err
+	}
 
+	return nil, c.handleRequest(request)
+}
+
+// handleRequest sends response to the request from the server
+func (c *Client) handleRequest(request *Request) error {
 	var (
 		response *Response
-		err      error
+		result   error
 	)
 
+	switch request.Method {
+	case MethodOptions, MethodGetParameter, MethodSetParameter:
+		// keep-alive from the server
+		response = NewResponse(StatusOK)
+
+	case MethodAnnounce:
+		// SDP update is not supported, stream continues as is
+		response = NewResponse(StatusOK)
+
+	case MethodRedirect:
+		response = NewResponse(StatusOK)
+		// Range defines when redirect takes effect, client is redirected immediately
+		result = &redirectError{location: request.Header.Get("Location")}
+
+	default:
+		response = NewResponse(StatusNotImplemented)
+	}
+
+	if cseq := request.Header.Get("CSeq"); cseq != "" {
+		response.Header.Set("CSeq", cseq)
+	}
+
+	if c.session != "" {
+		response.Header.Set("Session", c.session)
+	}
+
+	c.wlock.Lock()
+	err := WriteResponse(c.bw, response)
+	c.wlock.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return result
+}
+
+// ReadMessage reads RTSP message interleaved with binary frames.
+// Called by interleaved transport
+func (c *Client) ReadMessage(reader *bufio.Reader) error {
+	response, err := c.readMessage(reader)
+	if err != nil {
+		return err
+	}
+
+	if response != nil {
+		select {
+		case c.responses <- response:
+		default:
+			// nobody waits for response
+		}
+	}
+
+	return nil
+}
+
+// connect opens connection to the RTSP server and sends OPTIONS request.
+// Follows redirects
+func (c *Client) connect(ctx context.Context) error {
+	for {
+		if err := c.open(ctx); err != nil {
+			return err
+		}
+
+		request := &Request{
+			Method: MethodOptions,
+			URL:    c.URL,
+		}
+
+		response, err := c.do(ctx, request)
+		if err != nil {
+			if err = c.redirect(response, err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		c.lock.Lock()
+		c.public = parsePublic(response.Header.Get("Public"))
+		c.unsupported = nil
+		c.lock.Unlock()
+
+		return nil
+	}
+}
+
+// open opens connection to the RTSP server and makes transport
+func (c *Client) open(ctx context.Context) error {
+	var err error
+
+	c.session = ""
+	c.sessionTimeout = 0
+	c.transports = make(map[int]*TransportHeader)
+	c.setups = nil
+
 	if c.ConnectTimeout == 0 {
 		c.ConnectTimeout = defaultTimeout
 	}
@@ -146,46 +484,300 @@ func (c *Client) Start(ctx context.Context) error {
 		c.RequestTimeout = defaultTimeout
 	}
 
-	dialer := &net.Dialer{
-		Timeout: c.ConnectTimeout,
+	if c.FallbackTimeout == 0 {
+		c.FallbackTimeout = defaultTimeout
 	}
 
-	c.conn, err = dialer.DialContext(ctx, "tcp", c.URL.Host)
+	dialer, err := c.TCPConfig.dialer(c.ConnectTimeout)
 	if err != nil {
 		return err
 	}
 
-	c.br = bufio.NewReader(c.conn)
-	c.bw = bufio.NewWriter(c.conn)
+	target := c.URL
+	if proxy := c.proxyURL(); proxy != nil {
+		target = proxy
+	}
 
-	if c.UseTCP {
-		c.transport = NewTransportTCP(c.br)
+	var conn net.Conn
+	if c.HTTPTunnel {
+		conn, err = dialTunnel(ctx, dialer, target, c.TLSConfig, c.UserAgent)
 	} else {
-		c.transport = NewTransportUDP()
+		conn, err = dial(ctx, dialer, target, c.TLSConfig)
+	}
+	if err != nil {
+		return err
 	}
 
-	request := &Request{
-		Method: MethodOptions,
-		URL:    c.URL,
+	// writer is shared with WriteInterleaved
+	c.wlock.Lock()
+	c.conn = conn
+	c.br = bufio.NewReader(conn)
+	c.bw = bufio.NewWriter(conn)
+	c.wlock.Unlock()
+	c.demux = false
+	c.responses = make(chan *Response, 1)
+
+	if c.interleaved() {
+		transport := NewTransportTCPWithConn(c.br, c)
+		transport.EndOnBye = c.EndOnBye
+		transport.ReceiverReports = c.ReceiverReports
+		c.transport = transport
+	} else if c.Multicast {
+		transport := NewTransportUDPMulticast(c.MulticastInterface)
+		transport.Config = c.UDPConfig
+		transport.EndOnBye = c.EndOnBye
+		transport.ReceiverReports = c.ReceiverReports
+		c.transport = transport
+	} else {
+		var remoteIP net.IP
+		if addr, ok := c.conn.RemoteAddr().(*net.TCPAddr); ok {
+			remoteIP = addr.IP
+		}
+		transport := NewTransportUDPWithConfig(remoteIP, c.UDPConfig)
+		transport.EndOnBye = c.EndOnBye
+		transport.ReceiverReports = c.ReceiverReports
+		if c.HolePunch {
+			transport.HolePunchSchedule = c.HolePunchSchedule
+			if transport.HolePunchSchedule == nil {
+				transport.HolePunchSchedule = defaultHolePunchSchedule
+			}
+		}
+		c.transport = transport
+	}
+
+	return nil
+}
+
+// redirectError is returned on REDIRECT request from the server
+type redirectError struct {
+	location string
+}
+
+func (e *redirectError) Error() string {
+	return ErrRedirect.Error() + " to " + sanitizeLocation(e.location)
+}
+
+// sanitizeLocation returns location URL without credentials
+func sanitizeLocation(location string) string {
+	u, err := url.Parse(location)
+	if err != nil {
+		return "invalid location"
+	}
+
+	return sanitizeURL(u)
+}
+
+func (e *redirectError) Unwrap() error {
+	return ErrRedirect
+}
+
+// redirect closes connection and changes the client URL
+// to the Location from the 3xx response.
+// Returns err if response is not a redirect or the redirects limit is reached
+func (c *Client) redirect(response *Response, err error) error {
+	if response == nil {
+		return err
 	}
 
-	// OPTIONS
-	if _, err = c.do(ctx, request); err != nil {
+	switch response.StatusCode {
+	case StatusMovedPermanently,
+		StatusMovedTemporarily,
+		StatusSeeOther,
+		StatusUseProxy,
+		StatusTemporaryRedirect:
+	default:
 		return err
 	}
 
-	// DESCRIBE
-	request.Method = MethodDescribe
-	request.Header = http.Header{
-		"Accept": []string{SdpMimeType},
+	location := response.Header.Get("Location")
+	if location == "" || c.MaxRedirects < 0 {
+		return err
 	}
 
-	if response, err = c.do(ctx, request); err != nil {
+	if err := c.countRedirect(); err != nil {
 		return err
 	}
 
-	// Content-Base
-	controlURL := c.URL
+	u, e := c.URL.Parse(location)
+	if e != nil {
+		// parse error contains location with credentials
+		return fmt.Errorf("invalid redirect location")
+	}
+
+	c.Close()
+
+	if response.StatusCode == StatusUseProxy {
+		// request is sent to the proxy with the same URL
+		c.proxy = u
+		c.proxyAuth = nil
+	} else {
+		c.setURL(u)
+	}
+
+	return nil
+}
+
+// countRedirect checks the redirects limit
+func (c *Client) countRedirect() error {
+	limit := c.MaxRedirects
+	if limit == 0 {
+		limit = defaultMaxRedirects
+	}
+
+	if limit < 0 {
+		return ErrRedirect
+	}
+
+	if c.redirects >= limit {
+		return fmt.Errorf("%w: stopped after %d redirects", ErrTooManyRedirects, c.redirects)
+	}
+
+	c.redirects++
+
+	return nil
+}
+
+// setURL changes the client URL on redirect.
+// Credentials are kept only if scheme, host and port are not changed
+func (c *Client) setURL(u *url.URL) {
+	if strings.EqualFold(u.Scheme, c.URL.Scheme) && hostPort(u) == hostPort(c.URL) {
+		if u.User == nil {
+			u.User = c.URL.User
+		}
+	} else {
+		c.auth = nil
+	}
+
+	c.URL = u
+	c.proxy = nil
+}
+
+// proxyURL returns the proxy defined with 305 Use Proxy or the Proxy option.
+// Returns nil if connection is direct
+func (c *Client) proxyURL() *url.URL {
+	if c.proxy != nil {
+		return c.proxy
+	}
+
+	return c.Proxy
+}
+
+// authenticate makes authentication for the strongest supported challenge.
+// Returns nil if no challenges are supported or credentials are not defined
+func (c *Client) authenticate(ctx context.Context, challenges []string, proxy bool) (Auth, error) {
+	u := c.URL
+	if proxy {
+		if u = c.proxyURL(); u == nil {
+			return nil, nil
+		}
+	}
+
+	allowBasic := !c.BasicAuthTLSOnly || u.Scheme == "rtsps"
+	challenge := selectAuthChallenge(challenges, allowBasic)
+	if challenge == nil {
+		return nil, nil
+	}
+
+	if c.Credentials != nil {
+		login, password, err := c.Credentials.Credentials(ctx, stripCredentials(u), challenge.realm(), proxy)
+		if err != nil {
+			return nil, fmt.Errorf("credentials: %w", err)
+		}
+
+		return challenge.newAuth(login, password), nil
+	}
+
+	login := u.User.Username()
+	password, ok := u.User.Password()
+	if !ok {
+		return nil, nil
+	}
+
+	return challenge.newAuth(login, password), nil
+}
+
+// interleaved checks if media is delivered in the RTSP connection
+func (c *Client) interleaved() bool {
+	return c.UseTCP || c.HTTPTunnel || c.fallback
+}
+
+// parsePublic returns set of methods from the Public header.
+// Returns nil if header is not defined
+func parsePublic(header string) map[string]struct{} {
+	if header == "" {
+		return nil
+	}
+
+	public := make(map[string]struct{})
+	for _, method := range strings.Split(header, ",") {
+		if method = strings.TrimSpace(method); method != "" {
+			public[strings.ToUpper(method)] = struct{}{}
+		}
+	}
+
+	return public
+}
+
+// SupportsMethod checks if the method is supported by the server.
+// Method is supported if it is in the Public header of the OPTIONS response,
+// and had not failed before. If server did not send Public header,
+// all methods are assumed to be supported
+func (c *Client) SupportsMethod(method string) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if _, ok := c.unsupported[method]; ok {
+		return false
+	}
+
+	if c.public == nil {
+		return true
+	}
+
+	_, ok := c.public[method]
+	return ok
+}
+
+// Start connectes to the RTSP server and get SDP
+func (c *Client) Start(ctx context.Context) error {
+	c.redirects = 0
+	return c.start(ctx)
+}
+
+// start sends OPTIONS and DESCRIBE requests. Follows redirects
+func (c *Client) start(ctx context.Context) error {
+	var (
+		response *Response
+		err      error
+	)
+
+	for {
+		// OPTIONS
+		if err = c.connect(ctx); err != nil {
+			return err
+		}
+
+		// DESCRIBE
+		request := &Request{
+			Method: MethodDescribe,
+			URL:    c.URL,
+			Header: http.Header{
+				"Accept": []string{SdpMimeType},
+			},
+		}
+
+		if response, err = c.do(ctx, request); err == nil {
+			break
+		}
+
+		if err = c.redirect(response, err); err != nil {
+			return err
+		}
+	}
+
+	// Content-Base.
+	// Control URLs are defined without credentials
+	controlURL := stripCredentials(c.URL)
 	if v := response.Header.Get("content-base"); v != "" {
 		controlURL, err = url.Parse(v)
 		if err != nil {
@@ -208,71 +800,371 @@ func (c *Client) GetSDP() []*SdpItem {
 
 // Setup sends request to setup the stream delivery
 func (c *Client) Setup(ctx context.Context, mediaID int, control *url.URL) error {
+	return c.setup(ctx, mediaID, control, "")
+}
+
+// setup sends SETUP request with transport mode: empty for play or "record"
+func (c *Client) setup(ctx context.Context, mediaID int, control *url.URL, mode string) error {
 	if c.conn == nil {
 		return ErrClientClosed
 	}
 
-	transport, err := c.transport.Setup(mediaID)
+	value, err := c.transport.Setup(mediaID)
+	if err != nil {
+		return err
+	}
+
+	transport, err := ParseTransportHeader(value)
 	if err != nil {
 		return err
 	}
 
+	if mode != "" {
+		transport.Mode = mode
+	}
+
+	// secure profile for SRTP
+	if mediaID < len(c.sdp) && strings.HasPrefix(c.sdp[mediaID].Transport, "RTP/SAVP") {
+		transport.Profile = strings.Replace(transport.Profile, "RTP/AVP", "RTP/SAVP", 1)
+	}
+
 	request := &Request{
 		Method: MethodSetup,
 		URL:    control,
 		Header: http.Header{
-			"Transport": []string{transport},
+			"Transport": []string{transport.String()},
 		},
 	}
 
 	response, err := c.do(ctx, request)
 	if err != nil {
+		if response != nil && response.StatusCode == StatusUnsupportedTransport && c.canFallback() {
+			if err := c.fallbackTCP(ctx); err != nil {
+				return err
+			}
+
+			return c.setup(ctx, mediaID, control, mode)
+		}
+
 		return err
 	}
 
 	if c.session == "" {
-		c.session = getSession(response)
+		c.session, c.sessionTimeout = getSession(response)
+		if c.sessionTimeout == 0 {
+			c.sessionTimeout = defaultSessionTimeout
+		}
+	}
+
+	if v := response.Header.Get("Transport"); v != "" {
+		if transport, err = ParseTransportHeader(v); err != nil {
+			return err
+		}
+
+		if t, ok := c.transport.(TransportConfigurer); ok {
+			if err := t.Configure(mediaID, transport); err != nil {
+				return fmt.Errorf("invalid transport: %w", err)
+			}
+		}
+	}
+
+	// clock rate for the interarrival jitter in receiver reports
+	if t, ok := c.transport.(clockRateSetter); ok && mediaID < len(c.sdp) {
+		if clockRate := mediaClockRate(c.sdp[mediaID]); clockRate != 0 {
+			t.SetClockRate(mediaID, clockRate)
+		}
 	}
 
+	c.lock.Lock()
+	c.transports[mediaID] = transport
+	c.lock.Unlock()
+
+	c.setups = append(c.setups, setupRequest{
+		mediaID: mediaID,
+		control: control,
+		mode:    mode,
+	})
+
 	return nil
 }
 
-// Play sends request to start the stream delivery.
+// canFallback checks if client could switch from UDP to TCP transport
+func (c *Client) canFallback() bool {
+	return c.TCPFallback && !c.interleaved()
+}
+
+// fallbackTCP tears down the session, reconnects with the interleaved
+// TCP transport and replays SETUP requests
+func (c *Client) fallbackTCP(ctx context.Context) error {
+	setups := c.setups
+
+	if c.session != "" {
+		_ = c.Teardown(ctx)
+	}
+
+	c.Close()
+	c.fallback = true
+
+	if err := c.connect(ctx); err != nil {
+		return err
+	}
+
+	for _, s := range setups {
+		if err := c.setup(ctx, s.mediaID, s.control, s.mode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetTransport returns transport negotiated for the media in the SETUP request.
+// If server did not send Transport header, returns the requested transport.
+// Returns nil if media is not set up
+func (c *Client) GetTransport(mediaID int) *TransportHeader {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.transports[mediaID]
+}
+
+// GetStats returns the packet counters of the transport
+func (c *Client) GetStats() TransportStats {
+	if t, ok := c.transport.(TransportStatsProvider); ok {
+		return t.Stats()
+	}
+
+	return TransportStats{}
+}
+
+// GetSessionTimeout returns session timeout defined by the server in the SETUP response.
+// Default is 60 seconds
+func (c *Client) GetSessionTimeout() time.Duration {
+	return c.sessionTimeout
+}
+
+// Play sends request to start the stream delivery from the beginning.
 // Waits for ctx.Done or any error on transport.
-// For UDP transport it sends keep-alive requests each 30 second
+// Sends keep-alive requests in the half of the session timeout
 func (c *Client) Play(ctx context.Context, handler MediaHandler) error {
+	return c.PlayRange(ctx, handler, NewRangeNPT(0, 0))
+}
+
+// PlayRange sends request to start the stream delivery from the given range.
+// On first call starts the transport and waits for ctx.Done
+// or any error on transport like Play.
+// If stream is already playing or paused, sends request to resume or seek
+// and returns immediately, handler is ignored in this case
+func (c *Client) PlayRange(ctx context.Context, handler MediaHandler, r Range) error {
 	if c.conn == nil {
 		return ErrClientClosed
 	}
 
+	if err := c.play(ctx, r); err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	playing := c.playing
+	c.playing = true
+	c.lock.Unlock()
+
+	if playing {
+		return nil
+	}
+
+	return c.servePlay(ctx, handler, r)
+}
+
+// servePlay starts the transport and waits like serve.
+// Switches to TCP transport if no media received over UDP.
+// Reconnects to the new URL on REDIRECT request from the server
+// and replays SETUP and PLAY with the initial range r
+func (c *Client) servePlay(ctx context.Context, handler MediaHandler, r Range) error {
+	c.redirects = 0
+
+	for {
+		var received chan struct{}
+
+		if c.canFallback() {
+			watch := &mediaWatch{
+				MediaHandler: handler,
+				received:     make(chan struct{}),
+			}
+			received = watch.received
+			c.startTransport(watch)
+		} else {
+			c.startTransport(handler)
+		}
+
+		err := c.serve(ctx, received)
+
+		var redirect *redirectError
+		switch {
+		case err == errNoMedia:
+			err = c.fallbackTCP(ctx)
+		case errors.As(err, &redirect) && ctx.Err() == nil:
+			err = c.redirectSession(ctx, redirect.location)
+		default:
+			return err
+		}
+
+		if err == nil {
+			err = c.play(ctx, r)
+		}
+
+		if err != nil {
+			c.Close()
+			return err
+		}
+
+		c.lock.Lock()
+		c.playing = true
+		c.lock.Unlock()
+	}
+}
+
+// redirectSession connects to the location from REDIRECT request
+// and replays SETUP requests
+func (c *Client) redirectSession(ctx context.Context, location string) error {
+	if err := c.countRedirect(); err != nil {
+		return err
+	}
+
+	u, err := c.URL.Parse(location)
+	if err != nil || location == "" {
+		return fmt.Errorf("invalid redirect location")
+	}
+
+	setups := c.setups
+
+	c.Close()
+	c.setURL(u)
+
+	if err := c.start(ctx); err != nil {
+		return err
+	}
+
+	for _, s := range setups {
+		if s.mediaID >= len(c.sdp) {
+			return fmt.Errorf("media %d is not defined after redirect", s.mediaID)
+		}
+
+		if err := c.setup(ctx, s.mediaID, c.sdp[s.mediaID].URL, s.mode); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// play sends PLAY request
+func (c *Client) play(ctx context.Context, r Range) error {
 	request := &Request{
 		Method: MethodPlay,
 		URL:    c.URL,
 		Header: http.Header{
-			"Range": []string{"npt=0.000-"},
+			"Range": []string{r.String()},
 		},
 	}
 
-	if _, err := c.do(ctx, request); err != nil {
+	if c.Scale != 0 {
+		request.Header.Set("Scale", c.Scale.String())
+	}
+
+	if c.Speed != 0 {
+		request.Header.Set("Speed", c.Speed.String())
+	}
+
+	response, err := c.do(ctx, request)
+	if err != nil {
 		return err
 	}
 
+	c.lock.Lock()
+	c.setPlayInfo(response)
+	c.lock.Unlock()
+
+	return nil
+}
+
+// mediaWatch is the media handler to detect the first RTP packet
+type mediaWatch struct {
+	MediaHandler
+	once     sync.Once
+	received chan struct{}
+}
+
+func (w *mediaWatch) OnRTP(mediaID int, packet []byte) {
+	w.notify()
+	w.MediaHandler.OnRTP(mediaID, packet)
+}
+
+func (w *mediaWatch) OnRTPPacket(mediaID int, packet *RTPPacket) {
+	w.notify()
+	forwardRTP(w.MediaHandler, mediaID, packet)
+}
+
+func (w *mediaWatch) unwrap() MediaHandler {
+	return w.MediaHandler
+}
+
+func (w *mediaWatch) notify() {
+	w.once.Do(func() {
+		close(w.received)
+	})
+}
+
+// startTransport starts the transport.
+// Interleaved transport takes the connection reading
+func (c *Client) startTransport(handler MediaHandler) {
+	if c.interleaved() {
+		c.lock.Lock()
+		c.demux = true
+		c.lock.Unlock()
+	}
+
 	c.transport.Play(handler)
+}
+
+// serve waits for ctx.Done or any error on transport.
+// Sends keep-alive requests in the half of the session timeout.
+// Closes client before exit.
+// If received is defined, returns errNoMedia without closing client
+// if it is not closed in FallbackTimeout
+func (c *Client) serve(ctx context.Context, received <-chan struct{}) error {
+	timeout := c.sessionTimeout
+	if timeout == 0 {
+		timeout = defaultSessionTimeout
+	}
+
+	ticker := time.NewTicker(timeout / 2)
+	defer ticker.Stop()
 
-	var tickerC <-chan time.Time
-	if !c.UseTCP {
-		// For UDP transport send keep-alive requests every 30 seconds and Teardown before exit
-		ticker := time.NewTicker(30 * time.Second)
-		defer ticker.Stop()
-		tickerC = ticker.C
+	var fallback <-chan time.Time
+	if received != nil {
+		timer := time.NewTimer(c.FallbackTimeout)
+		defer timer.Stop()
+		fallback = timer.C
 	}
 
 	for {
 		select {
-		case <-tickerC:
-			// only for UDP
+		case <-received:
+			received = nil
+			fallback = nil
+			continue
+
+		case <-fallback:
+			return errNoMedia
+
+		case <-ticker.C:
 			if err := c.Ping(ctx); err != nil {
 				c.Close()
+				if ctx.Err() != nil {
+					// canceled during request
+					return nil
+				}
 				return err
 			}
 			continue
@@ -288,19 +1180,159 @@ func (c *Client) Play(ctx context.Context, handler MediaHandler) error {
 	}
 }
 
-// Ping sends request to keep the connection alive
-func (c *Client) Ping(ctx context.Context) error {
+// Pause sends request to halt the stream delivery temporarily.
+// Session and transport are kept, use PlayRange to resume or seek
+func (c *Client) Pause(ctx context.Context) error {
 	if c.conn == nil {
 		return ErrClientClosed
 	}
 
 	request := &Request{
-		Method: MethodGetParameter,
+		Method: MethodPause,
 		URL:    c.URL,
 	}
 
-	_, err := c.do(ctx, request)
-	return err
+	response, err := c.do(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	c.setRange(response)
+	c.lock.Unlock()
+
+	return nil
+}
+
+// setRange keeps Range from PLAY or PAUSE response
+func (c *Client) setRange(response *Response) {
+	if v := response.Header.Get("Range"); v != "" {
+		if r, err := ParseRange(v); err == nil {
+			c.playRange = r
+		}
+	}
+}
+
+// setPlayInfo keeps Range, RTP-Info, Scale and Speed from PLAY response.
+// Values not returned by the server are reset
+func (c *Client) setPlayInfo(response *Response) {
+	c.setRange(response)
+
+	c.rtpInfo = nil
+	if v := response.Header.Get("RTP-Info"); v != "" {
+		c.rtpInfo = ParseRTPInfo(v)
+	}
+
+	c.scale = 0
+	if v := response.Header.Get("Scale"); v != "" {
+		if scale, err := ParseScale(v); err == nil {
+			c.scale = scale
+		}
+	}
+
+	c.speed = 0
+	if v := response.Header.Get("Speed"); v != "" {
+		if speed, err := ParseSpeed(v); err == nil {
+			c.speed = speed
+		}
+	}
+}
+
+// GetRange returns range from the last PLAY or PAUSE response
+func (c *Client) GetRange() Range {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.playRange
+}
+
+// GetScale returns play rate accepted by the server in the last PLAY response.
+// Returns 0 if server did not return Scale header
+func (c *Client) GetScale() Scale {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.scale
+}
+
+// GetSpeed returns delivery speed accepted by the server in the last PLAY response.
+// Returns 0 if server did not return Speed header
+func (c *Client) GetSpeed() Speed {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.speed
+}
+
+// GetRTPInfo returns stream positions from the last PLAY response
+func (c *Client) GetRTPInfo() []*RTPInfo {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.rtpInfo
+}
+
+// keepAliveMethods is the list of methods for keep-alive requests in order of preference
+var keepAliveMethods = []string{
+	MethodGetParameter,
+	MethodOptions,
+	MethodSetParameter,
+}
+
+// Ping sends request to keep the connection alive.
+// GET_PARAMETER is preferred if server supports it,
+// OPTIONS is always used as fallback, because all servers should support it.
+// If server responds that method is not supported, next method is used.
+// Other error statuses are returned as is
+func (c *Client) Ping(ctx context.Context) error {
+	if c.conn == nil {
+		return ErrClientClosed
+	}
+
+	var err error
+
+	for _, method := range keepAliveMethods {
+		// OPTIONS is required for servers, even if not listed in the Public header
+		if method != MethodOptions && !c.SupportsMethod(method) {
+			continue
+		}
+
+		request := &Request{
+			Method: method,
+			URL:    c.URL,
+		}
+
+		if _, err = c.do(ctx, request); err == nil {
+			return nil
+		}
+
+		var statusErr *StatusError
+		if !errors.As(err, &statusErr) || !isMethodNotSupported(statusErr.Code) {
+			// connection error or the session is not valid
+			return err
+		}
+
+		// server does not support method. try next one
+		c.lock.Lock()
+		if c.unsupported == nil {
+			c.unsupported = make(map[string]struct{})
+		}
+		c.unsupported[method] = struct{}{}
+		c.lock.Unlock()
+	}
+
+	return fmt.Errorf("%w: %v", ErrKeepAliveNotSupported, err)
+}
+
+// isMethodNotSupported checks if response status means
+// that server does not support the request method
+func isMethodNotSupported(code int) bool {
+	switch code {
+	case StatusMethodNotAllowed, StatusNotImplemented, StatusOptionNotSupported:
+		return true
+	default:
+		return false
+	}
 }
 
 // Teardown sends request to stop the stream delivery
@@ -318,16 +1350,31 @@ func (c *Client) Teardown(ctx context.Context) error {
 	return err
 }
 
+// WriteInterleaved sends RTP or RTCP packet in the interleaved binary frame
+// https://datatracker.ietf.org/doc/html/rfc2326#section-10.12
+func (c *Client) WriteInterleaved(channel int, packet []byte) error {
+	c.wlock.Lock()
+	defer c.wlock.Unlock()
+
+	return writeInterleaved(c.bw, channel, packet)
+}
+
 // Close closes the connection and closes all transports.
 // Should be called after Play() finished
 func (c *Client) Close() {
+	// connection is closed first to break blocked interleaved writes
+	if c.conn != nil {
+		c.conn.Close()
+	}
+
 	if c.transport != nil {
 		c.transport.Close()
 		c.transport = nil
 	}
 
-	if c.conn != nil {
-		c.conn.Close()
-		c.conn = nil
-	}
+	c.conn = nil
+
+	c.lock.Lock()
+	c.playing = false
+	c.lock.Unlock()
 }
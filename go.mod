@@ -2,7 +2,11 @@ module github.com/cesbo/go-rtsp
 
 go 1.19
 
-require github.com/stretchr/testify v1.7.0
+require (
+	github.com/stretchr/testify v1.7.0
+	golang.org/x/net v0.35.0
+	golang.org/x/sys v0.30.0
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
@@ -0,0 +1,417 @@
+package rtsp
+
+import (
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// Default jitter buffer parameters
+const (
+	defaultJitterLatency = 200 * time.Millisecond
+	defaultJitterSize    = 512
+)
+
+// Sequence number limits to detect the source restart
+// https://datatracker.ietf.org/doc/html/rfc3550#appendix-A.1
+const (
+	jitterMaxDropout  = 3000
+	jitterMaxMisorder = 100
+)
+
+// LossHandler is the optional MediaHandler interface
+// to be notified about lost RTP packets.
+// seq is the sequence number of the first lost packet,
+// count is the number of lost packets
+type LossHandler interface {
+	OnLoss(mediaID int, seq uint16, count int)
+}
+
+// JitterBuffer is the MediaHandler to reorder RTP packets by sequence number.
+// Duplicates and late packets are dropped. Missing packets are waited
+// for Latency and then reported to the LossHandler if handler implements it.
+// Two sequential packets out of the sequence window restart the stream.
+// Packets are copied, so handler could keep them
+type JitterBuffer struct {
+	// Latency is the maximum time to wait for the missing packet.
+	// Default is 200ms
+	Latency time.Duration
+	// Size is the maximum number of packets in the buffer for each media.
+	// Rounded up to the power of two. Default is 512
+	Size int
+
+	handler MediaHandler
+
+	lock    sync.Mutex
+	streams map[int]*jitterStream
+	timer   *time.Timer
+	closed  bool
+	// events is the packets and losses to deliver after unlock
+	events []jitterEvent
+	// dispatching is set while one of callers delivers events.
+	// Handler is called without lock, so it could close the buffer
+	dispatching bool
+	rtp         rtpReceiver
+
+	// timeNow returns current time. Defined in tests
+	timeNow func() time.Time
+}
+
+// jitterStream is the buffer for one media
+type jitterStream struct {
+	ssrc uint32
+	// next is the sequence number of the next packet to deliver
+	next uint16
+	// slots is the ring buffer indexed by sequence number
+	slots []jitterPacket
+	count int
+	// bad is the expected sequence number of the next packet
+	// out of the window to restart the stream. -1 if not defined
+	bad int
+}
+
+type jitterPacket struct {
+	seq     uint16
+	data    []byte
+	arrival time.Time
+}
+
+// jitterEvent is the packet, the loss, or the discontinuity
+// to deliver to the handler
+type jitterEvent struct {
+	mediaID int
+	// data is the packet. nil for the loss
+	data []byte
+	// seq and count are the lost packets
+	seq   uint16
+	count int
+	// discontinuity is set to deliver err to the DiscontinuityHandler
+	discontinuity bool
+	err           error
+}
+
+// NewJitterBuffer makes a new jitter buffer for the handler
+func NewJitterBuffer(handler MediaHandler) *JitterBuffer {
+	return &JitterBuffer{
+		handler: handler,
+		streams: make(map[int]*jitterStream),
+	}
+}
+
+func (j *JitterBuffer) now() time.Time {
+	if j.timeNow != nil {
+		return j.timeNow()
+	}
+
+	return time.Now()
+}
+
+func (j *JitterBuffer) latency() time.Duration {
+	if j.Latency <= 0 {
+		return defaultJitterLatency
+	}
+
+	return j.Latency
+}
+
+// size returns the ring buffer size.
+// Power of two keeps slot index continuous on sequence number wraparound
+func (j *JitterBuffer) size() int {
+	if j.Size <= 0 {
+		return defaultJitterSize
+	}
+
+	size := 1
+	for size < j.Size && size < 1<<15 {
+		size <<= 1
+	}
+
+	return size
+}
+
+// OnRTP puts packet to the buffer and delivers packets ready in order
+func (j *JitterBuffer) OnRTP(mediaID int, packet []byte) {
+	if len(packet) < rtpHeaderSize || packet[0]>>6 != RTPVersion {
+		return
+	}
+
+	seq := binary.BigEndian.Uint16(packet[2:4])
+	ssrc := binary.BigEndian.Uint32(packet[8:12])
+	now := j.now()
+
+	j.lock.Lock()
+
+	if !j.closed {
+		j.put(mediaID, seq, ssrc, packet, now)
+	}
+
+	j.dispatch()
+}
+
+// put adds packet to the stream and releases packets ready in order
+func (j *JitterBuffer) put(mediaID int, seq uint16, ssrc uint32, packet []byte, now time.Time) {
+	s := j.streams[mediaID]
+	if s != nil && s.ssrc != ssrc {
+		// new source. deliver the rest of previous source
+		j.flush(mediaID, s)
+		s = nil
+	}
+
+	if s != nil {
+		switch delta := seq - s.next; {
+		case delta < jitterMaxDropout:
+			// in order or with acceptable gap
+			s.bad = -1
+		case delta >= 1<<16-jitterMaxMisorder:
+			// late packet, already delivered or reported as lost
+			return
+		case int(seq) != s.bad:
+			// out of the window. wait for the next packet to restart
+			s.bad = int(seq + 1)
+			return
+		default:
+			// two sequential packets out of the window. source is restarted
+			j.flush(mediaID, s)
+			s = nil
+		}
+	}
+
+	if s == nil {
+		s = &jitterStream{
+			ssrc:  ssrc,
+			next:  seq,
+			slots: make([]jitterPacket, j.size()),
+			bad:   -1,
+		}
+		j.streams[mediaID] = s
+	}
+
+	if delta := int(seq - s.next); delta >= len(s.slots) {
+		// no space in the buffer. skip the oldest packets
+		j.skip(mediaID, s, seq-uint16(len(s.slots))+1)
+	}
+
+	slot := &s.slots[int(seq)%len(s.slots)]
+	if slot.data != nil {
+		// duplicate
+		return
+	}
+
+	slot.seq = seq
+	slot.data = append([]byte(nil), packet...)
+	slot.arrival = now
+	s.count++
+
+	j.release(mediaID, s, now)
+	j.schedule(now)
+}
+
+// OnRTCP delivers packet to the handler without buffering
+func (j *JitterBuffer) OnRTCP(mediaID int, packet []byte) {
+	j.handler.OnRTCP(mediaID, packet)
+}
+
+// OnDiscontinuity delivers buffered packets of the previous session
+// and resets the buffer
+func (j *JitterBuffer) OnDiscontinuity(err error) {
+	j.lock.Lock()
+	for mediaID, s := range j.streams {
+		j.flush(mediaID, s)
+	}
+
+	// delivered after packets of the previous session
+	j.events = append(j.events, jitterEvent{
+		discontinuity: true,
+		err:           err,
+	})
+
+	j.dispatch()
+}
+
+// Close drops buffered packets and stops the timer
+func (j *JitterBuffer) Close() {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	j.closed = true
+	j.streams = make(map[int]*jitterStream)
+	j.events = nil
+
+	if j.timer != nil {
+		j.timer.Stop()
+	}
+}
+
+// deliver takes packet from the slot to send it to the handler
+func (j *JitterBuffer) deliver(mediaID int, s *jitterStream, slot *jitterPacket) {
+	j.events = append(j.events, jitterEvent{
+		mediaID: mediaID,
+		data:    slot.data,
+	})
+
+	*slot = jitterPacket{}
+	s.count--
+	s.next++
+}
+
+// loss takes lost packets to report them to the handler
+func (j *JitterBuffer) loss(mediaID int, seq uint16, count int) {
+	if count > 0 {
+		j.events = append(j.events, jitterEvent{
+			mediaID: mediaID,
+			seq:     seq,
+			count:   count,
+		})
+	}
+}
+
+// dispatch sends collected events to the handler and unlocks the buffer.
+// Events are delivered in order by one caller at a time,
+// if other caller is dispatching, events are left for it
+func (j *JitterBuffer) dispatch() {
+	if j.dispatching {
+		j.lock.Unlock()
+		return
+	}
+
+	j.dispatching = true
+
+	for len(j.events) != 0 {
+		events := j.events
+		j.events = nil
+
+		j.lock.Unlock()
+		for _, e := range events {
+			j.send(e)
+		}
+		j.lock.Lock()
+	}
+
+	j.dispatching = false
+	j.lock.Unlock()
+}
+
+// send delivers event to the handler
+func (j *JitterBuffer) send(e jitterEvent) {
+	switch {
+	case e.discontinuity:
+		if h, ok := j.handler.(DiscontinuityHandler); ok {
+			h.OnDiscontinuity(e.err)
+		}
+	case e.data == nil:
+		if h, ok := j.handler.(LossHandler); ok {
+			h.OnLoss(e.mediaID, e.seq, e.count)
+		}
+	case j.rtp.parse(j.handler, e.data):
+		j.rtp.deliver(j.handler, e.mediaID, e.data)
+	}
+}
+
+// release delivers packets in order.
+// Gap is reported as loss if next packet waits longer than latency
+func (j *JitterBuffer) release(mediaID int, s *jitterStream, now time.Time) {
+	for s.count > 0 {
+		slot := &s.slots[int(s.next)%len(s.slots)]
+		if slot.data != nil {
+			j.deliver(mediaID, s, slot)
+			continue
+		}
+
+		// first packet after the gap
+		first := j.first(s)
+		if now.Sub(first.arrival) < j.latency() {
+			return
+		}
+
+		j.skip(mediaID, s, first.seq)
+	}
+}
+
+// first returns the first buffered packet after next
+func (j *JitterBuffer) first(s *jitterStream) *jitterPacket {
+	for i := 0; i < len(s.slots); i++ {
+		slot := &s.slots[int(s.next+uint16(i))%len(s.slots)]
+		if slot.data != nil {
+			return slot
+		}
+	}
+
+	return nil
+}
+
+// skip delivers buffered packets before seq
+// and reports missing packets as lost
+func (j *JitterBuffer) skip(mediaID int, s *jitterStream, seq uint16) {
+	lost := 0
+
+	for s.next != seq {
+		slot := &s.slots[int(s.next)%len(s.slots)]
+		if slot.data == nil {
+			lost++
+			s.next++
+			continue
+		}
+
+		j.loss(mediaID, s.next-uint16(lost), lost)
+		lost = 0
+		j.deliver(mediaID, s, slot)
+	}
+
+	j.loss(mediaID, s.next-uint16(lost), lost)
+}
+
+// flush delivers all buffered packets of the stream
+func (j *JitterBuffer) flush(mediaID int, s *jitterStream) {
+	if s.count > 0 {
+		last := s.next
+		for i := 0; i < len(s.slots); i++ {
+			if slot := &s.slots[int(s.next+uint16(i))%len(s.slots)]; slot.data != nil {
+				last = slot.seq
+			}
+		}
+		j.skip(mediaID, s, last+1)
+	}
+
+	delete(j.streams, mediaID)
+}
+
+// schedule starts timer to release packets waiting for the missing one
+func (j *JitterBuffer) schedule(now time.Time) {
+	var deadline time.Time
+
+	for _, s := range j.streams {
+		if s.count == 0 {
+			continue
+		}
+
+		t := j.first(s).arrival.Add(j.latency())
+		if deadline.IsZero() || t.Before(deadline) {
+			deadline = t
+		}
+	}
+
+	if deadline.IsZero() {
+		return
+	}
+
+	if j.timer == nil {
+		j.timer = time.AfterFunc(deadline.Sub(now), j.onTimer)
+	} else {
+		j.timer.Reset(deadline.Sub(now))
+	}
+}
+
+func (j *JitterBuffer) onTimer() {
+	j.lock.Lock()
+
+	if !j.closed {
+		now := j.now()
+		for mediaID, s := range j.streams {
+			j.release(mediaID, s, now)
+		}
+
+		j.schedule(now)
+	}
+
+	j.dispatch()
+}
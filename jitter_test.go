@@ -0,0 +1,231 @@
+package rtsp
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testLossHandler records delivered sequence numbers and loss events
+type testLossHandler struct {
+	testHandler
+	lock   sync.Mutex
+	events []string
+}
+
+func (h *testLossHandler) OnRTP(mediaID int, packet []byte) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	seq := uint16(packet[2])<<8 | uint16(packet[3])
+	h.events = append(h.events, fmt.Sprintf("%d:%d", mediaID, seq))
+}
+
+func (h *testLossHandler) OnLoss(mediaID int, seq uint16, count int) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.events = append(h.events, fmt.Sprintf("%d:loss %d+%d", mediaID, seq, count))
+}
+
+func (h *testLossHandler) get() []string {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return append([]string(nil), h.events...)
+}
+
+func TestJitterBuffer_Reorder(t *testing.T) {
+	assert := assert.New(t)
+
+	handler := &testLossHandler{}
+	j := NewJitterBuffer(handler)
+	j.Latency = time.Hour
+	defer j.Close()
+
+	for _, seq := range []uint16{65534, 0, 65535, 0, 65533, 2, 1} {
+		j.OnRTP(0, testRTPHeader(seq, 0, 1))
+	}
+
+	assert.Equal(
+		[]string{"0:65534", "0:65535", "0:0", "0:1", "0:2"},
+		handler.get(),
+	)
+}
+
+func TestJitterBuffer_Latency(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Unix(0, 0)
+
+	handler := &testLossHandler{}
+	j := NewJitterBuffer(handler)
+	j.Latency = time.Hour
+	j.timeNow = func() time.Time { return now }
+	defer j.Close()
+
+	j.OnRTP(0, testRTPHeader(10, 0, 1))
+	j.OnRTP(0, testRTPHeader(13, 0, 1))
+	j.OnRTP(1, testRTPHeader(20, 0, 2))
+	j.OnRTP(0, testRTPHeader(14, 0, 1))
+
+	assert.Equal([]string{"0:10", "1:20"}, handler.get())
+
+	now = now.Add(time.Hour)
+	j.onTimer()
+
+	assert.Equal(
+		[]string{"0:10", "1:20", "0:loss 11+2", "0:13", "0:14"},
+		handler.get(),
+	)
+
+	// lost packet arrived too late
+	j.OnRTP(0, testRTPHeader(12, 0, 1))
+	j.OnRTP(0, testRTPHeader(15, 0, 1))
+	assert.Equal(
+		[]string{"0:10", "1:20", "0:loss 11+2", "0:13", "0:14", "0:15"},
+		handler.get(),
+	)
+}
+
+func TestJitterBuffer_Timer(t *testing.T) {
+	assert := assert.New(t)
+
+	handler := &testLossHandler{}
+	j := NewJitterBuffer(handler)
+	j.Latency = 20 * time.Millisecond
+	defer j.Close()
+
+	j.OnRTP(0, testRTPHeader(1, 0, 1))
+	j.OnRTP(0, testRTPHeader(3, 0, 1))
+
+	assert.Eventually(
+		func() bool { return len(handler.get()) == 3 },
+		time.Second,
+		5*time.Millisecond,
+	)
+	assert.Equal(
+		[]string{"0:1", "0:loss 2+1", "0:3"},
+		handler.get(),
+	)
+}
+
+func TestJitterBuffer_Resync(t *testing.T) {
+	assert := assert.New(t)
+
+	handler := &testLossHandler{}
+	j := NewJitterBuffer(handler)
+	j.Latency = time.Hour
+	defer j.Close()
+
+	// single packet out of the window is dropped,
+	// two sequential packets restart the stream
+	for _, seq := range []uint16{10, 11, 40000, 12, 40001, 40002, 40003, 100, 101} {
+		j.OnRTP(0, testRTPHeader(seq, 0, 1))
+	}
+
+	assert.Equal(
+		[]string{"0:10", "0:11", "0:12", "0:40002", "0:40003", "0:101"},
+		handler.get(),
+	)
+}
+
+// testCloseHandler closes the jitter buffer on the first packet
+type testCloseHandler struct {
+	testLossHandler
+	j *JitterBuffer
+}
+
+func (h *testCloseHandler) OnRTP(mediaID int, packet []byte) {
+	h.testLossHandler.OnRTP(mediaID, packet)
+	h.j.Close()
+}
+
+func TestJitterBuffer_Close(t *testing.T) {
+	assert := assert.New(t)
+
+	handler := &testCloseHandler{}
+	j := NewJitterBuffer(handler)
+	j.Latency = time.Hour
+	handler.j = j
+
+	j.OnRTP(0, testRTPHeader(1, 0, 1))
+	j.OnRTP(0, testRTPHeader(2, 0, 1))
+
+	assert.Equal([]string{"0:1"}, handler.get())
+}
+
+// testSlowCloseHandler closes the buffer on the packet
+// while other goroutine delivers packets
+type testSlowCloseHandler struct {
+	testHandler
+	j *JitterBuffer
+}
+
+func (h *testSlowCloseHandler) OnRTP(mediaID int, packet []byte) {
+	if mediaID == 0 && packet[3] == 10 {
+		time.Sleep(10 * time.Millisecond)
+		h.j.Close()
+	}
+}
+
+func TestJitterBuffer_ConcurrentClose(t *testing.T) {
+	handler := &testSlowCloseHandler{}
+	j := NewJitterBuffer(handler)
+	j.Latency = time.Hour
+	handler.j = j
+
+	var wg sync.WaitGroup
+	for mediaID := 0; mediaID < 2; mediaID++ {
+		wg.Add(1)
+		go func(mediaID int) {
+			defer wg.Done()
+			for seq := uint16(0); seq < 1000; seq++ {
+				j.OnRTP(mediaID, testRTPHeader(seq, 0, uint32(mediaID)))
+			}
+		}(mediaID)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("deadlock")
+	}
+}
+
+func TestJitterBuffer_Size(t *testing.T) {
+	assert := assert.New(t)
+
+	handler := &testLossHandler{}
+	j := NewJitterBuffer(handler)
+	j.Latency = time.Hour
+	j.Size = 3
+	defer j.Close()
+
+	// size rounded up to 4
+	for _, seq := range []uint16{1, 3, 4, 5, 7} {
+		j.OnRTP(0, testRTPHeader(seq, 0, 1))
+	}
+
+	assert.Equal(
+		[]string{"0:1", "0:loss 2+1", "0:3", "0:4", "0:5"},
+		handler.get(),
+	)
+
+	// new source flushes the buffer
+	j.OnRTP(0, testRTPHeader(100, 0, 2))
+
+	assert.Equal(
+		[]string{"0:1", "0:loss 2+1", "0:3", "0:4", "0:5", "0:loss 6+1", "0:7", "0:100"},
+		handler.get(),
+	)
+}
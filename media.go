@@ -3,9 +3,19 @@ package rtsp
 import "strings"
 
 type Media interface {
+	// ParseFMTP parses format parameters from the SDP a=fmtp attribute
 	ParseFMTP(line string)
 }
 
+// SdpFormatter is the optional Media interface
+// to make media attributes for FormatSDP
+type SdpFormatter interface {
+	// FormatRTPMap returns encoding name and clock rate for the SDP a=rtpmap attribute
+	FormatRTPMap() string
+	// FormatFMTP returns format parameters for the SDP a=fmtp attribute
+	FormatFMTP() string
+}
+
 func NewMedia(mediaType string, clockRate int) Media {
 	switch strings.ToLower(mediaType) {
 	case "mpeg4-generic":
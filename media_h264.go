@@ -3,6 +3,7 @@ package rtsp
 import (
 	"encoding/base64"
 	"encoding/hex"
+	"fmt"
 	"strconv"
 	"strings"
 )
@@ -65,3 +66,27 @@ func (m *MediaH264) ParseFMTP(line string) {
 		}
 	}
 }
+
+func (m *MediaH264) FormatRTPMap() string {
+	return fmt.Sprintf("H264/%d", m.ClockRate)
+}
+
+func (m *MediaH264) FormatFMTP() string {
+	params := []string{
+		fmt.Sprintf("packetization-mode=%d", m.PacketizationMode),
+	}
+
+	if len(m.ProfileLevelID) == 3 {
+		params = append(params, "profile-level-id="+hex.EncodeToString(m.ProfileLevelID))
+	}
+
+	if len(m.SPS) != 0 && len(m.PPS) != 0 {
+		params = append(params, fmt.Sprintf(
+			"sprop-parameter-sets=%s,%s",
+			base64.StdEncoding.EncodeToString(m.SPS),
+			base64.StdEncoding.EncodeToString(m.PPS),
+		))
+	}
+
+	return strings.Join(params, ";")
+}
@@ -2,6 +2,7 @@ package rtsp
 
 import (
 	"encoding/base64"
+	"fmt"
 	"strconv"
 	"strings"
 )
@@ -60,3 +61,29 @@ func (m *MediaH265) ParseFMTP(line string) {
 		}
 	}
 }
+
+func (m *MediaH265) FormatRTPMap() string {
+	return fmt.Sprintf("H265/%d", m.ClockRate)
+}
+
+func (m *MediaH265) FormatFMTP() string {
+	var params []string
+
+	if m.LevelID != 0 {
+		params = append(params, fmt.Sprintf("level-id=%d", m.LevelID))
+	}
+
+	if len(m.VPS) != 0 {
+		params = append(params, "sprop-vps="+base64.StdEncoding.EncodeToString(m.VPS))
+	}
+
+	if len(m.SPS) != 0 {
+		params = append(params, "sprop-sps="+base64.StdEncoding.EncodeToString(m.SPS))
+	}
+
+	if len(m.PPS) != 0 {
+		params = append(params, "sprop-pps="+base64.StdEncoding.EncodeToString(m.PPS))
+	}
+
+	return strings.Join(params, ";")
+}
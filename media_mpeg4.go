@@ -2,6 +2,7 @@ package rtsp
 
 import (
 	"encoding/hex"
+	"fmt"
 	"strconv"
 	"strings"
 )
@@ -52,3 +53,29 @@ func (m *MediaMPEG4) ParseFMTP(line string) {
 		}
 	}
 }
+
+func (m *MediaMPEG4) FormatRTPMap() string {
+	return fmt.Sprintf("mpeg4-generic/%d", m.ClockRate)
+}
+
+func (m *MediaMPEG4) FormatFMTP() string {
+	params := []string{
+		"streamtype=5",
+		fmt.Sprintf("profile-level-id=%d", m.ProfileLevelID),
+	}
+
+	if m.Mode != "" {
+		params = append(params, "mode="+m.Mode)
+	}
+
+	if m.Mode == "AAC-hbr" {
+		// AU-headers for the high bit-rate AAC
+		params = append(params, "sizelength=13", "indexlength=3", "indexdeltalength=3")
+	}
+
+	if len(m.Config) != 0 {
+		params = append(params, "config="+hex.EncodeToString(m.Config))
+	}
+
+	return strings.Join(params, ";")
+}
@@ -0,0 +1,173 @@
+package rtsp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+)
+
+// Method Definitions for publishing
+const (
+	MethodAnnounce = "ANNOUNCE"
+	MethodRecord   = "RECORD"
+)
+
+var ErrNotRecording = fmt.Errorf("not recording")
+
+type nopHandler struct{}
+
+func (nopHandler) OnRTP(mediaID int, packet []byte)  {}
+func (nopHandler) OnRTCP(mediaID int, packet []byte) {}
+
+// Publisher sends media streams to the RTSP server
+// with ANNOUNCE and RECORD requests.
+// https://datatracker.ietf.org/doc/html/rfc2326#section-10.3
+type Publisher struct {
+	client    *Client
+	transport TransportWriter
+	recording atomic.Bool
+}
+
+// NewPublisher makes a new publisher.
+// Client defines the server URL, transport and timeouts
+func NewPublisher(client *Client) *Publisher {
+	return &Publisher{
+		client: client,
+	}
+}
+
+// Start connects to the RTSP server and announces media streams.
+// Sets control URL for each SDP item
+func (p *Publisher) Start(ctx context.Context, items []*SdpItem) error {
+	c := p.client
+
+	// OPTIONS
+	if err := c.connect(ctx); err != nil {
+		return err
+	}
+
+	for mediaID, item := range items {
+		control, err := parse_a_control(c.URL, fmt.Sprintf("trackID=%d", mediaID))
+		if err != nil {
+			return err
+		}
+		item.URL = control
+	}
+
+	var localIP net.IP
+	if addr, ok := c.conn.LocalAddr().(*net.TCPAddr); ok {
+		localIP = addr.IP
+	}
+
+	body, err := FormatSDP(localIP, items)
+	if err != nil {
+		return err
+	}
+
+	// ANNOUNCE
+	request := &Request{
+		Method: MethodAnnounce,
+		URL:    c.URL,
+		Header: http.Header{
+			"Content-Type": []string{SdpMimeType},
+		},
+		Body: body,
+	}
+
+	if _, err := c.do(ctx, request); err != nil {
+		return err
+	}
+
+	c.sdp = items
+
+	return nil
+}
+
+// Setup sends requests to setup delivery for all announced media streams
+func (p *Publisher) Setup(ctx context.Context) error {
+	c := p.client
+
+	for mediaID, item := range c.sdp {
+		if err := c.setup(ctx, mediaID, item.URL, "record"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Record sends request to start recording.
+// Waits for ctx.Done or any error on transport.
+// Handler receives packets from the server, like RTCP receiver reports,
+// could be nil
+func (p *Publisher) Record(ctx context.Context, handler MediaHandler) error {
+	c := p.client
+
+	if c.conn == nil {
+		return ErrClientClosed
+	}
+
+	writer, ok := c.transport.(TransportWriter)
+	if !ok {
+		return fmt.Errorf("transport does not support sending packets")
+	}
+
+	request := &Request{
+		Method: MethodRecord,
+		URL:    c.URL,
+		Header: http.Header{
+			"Range": []string{NewRangeNPT(0, 0).String()},
+		},
+	}
+
+	if _, err := c.do(ctx, request); err != nil {
+		return err
+	}
+
+	if handler == nil {
+		handler = nopHandler{}
+	}
+
+	// transport should be defined before recording flag
+	p.transport = writer
+	c.startTransport(handler)
+	p.recording.Store(true)
+	defer p.recording.Store(false)
+
+	return c.serve(ctx, nil)
+}
+
+// WriteRTP sends RTP packet to the server.
+// Returns ErrNotRecording if Record is not started yet
+func (p *Publisher) WriteRTP(mediaID int, packet []byte) error {
+	if !p.recording.Load() {
+		return ErrNotRecording
+	}
+
+	return p.transport.WriteRTP(mediaID, packet)
+}
+
+// WriteRTCP sends RTCP packet to the server.
+// Returns ErrNotRecording if Record is not started yet
+func (p *Publisher) WriteRTCP(mediaID int, packet []byte) error {
+	if !p.recording.Load() {
+		return ErrNotRecording
+	}
+
+	return p.transport.WriteRTCP(mediaID, packet)
+}
+
+// Teardown sends request to stop recording
+func (p *Publisher) Teardown(ctx context.Context) error {
+	p.recording.Store(false)
+
+	return p.client.Teardown(ctx)
+}
+
+// Close closes the connection and closes all transports.
+func (p *Publisher) Close() {
+	p.recording.Store(false)
+	p.client.Close()
+}
@@ -0,0 +1,106 @@
+package rtsp
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublisher_Record(t *testing.T) {
+	assert := assert.New(t)
+
+	media, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if !assert.NoError(err) {
+		return
+	}
+	defer media.Close()
+
+	mediaPort := media.LocalAddr().(*net.UDPAddr).Port
+
+	announce := make(chan *Request, 1)
+	transport := make(chan string, 1)
+
+	addr, closeServer := testServer(
+		func(ctx context.Context, r *Request, w *bufio.Writer) {
+			w.WriteString("RTSP/1.0 200 OK\r\n")
+			w.WriteString("CSeq: " + r.Header.Get("CSeq") + "\r\n")
+
+			switch r.Method {
+			case MethodAnnounce:
+				announce <- r
+			case MethodSetup:
+				transport <- r.Header.Get("Transport")
+				w.WriteString("Session: 12345678\r\n")
+				w.WriteString("Transport: RTP/AVP;unicast;server_port=" + strconv.Itoa(mediaPort) + "-" + strconv.Itoa(mediaPort+1) + "\r\n")
+			}
+
+			w.WriteString("\r\n")
+			w.Flush()
+		},
+	)
+	defer closeServer()
+
+	u, _ := url.Parse("rtsp://" + addr + "/live")
+	p := NewPublisher(&Client{URL: u})
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	items := []*SdpItem{
+		{
+			Type:   "video",
+			Format: 96,
+			Media:  NewMediaH264(90000),
+		},
+	}
+
+	if !assert.NoError(p.Start(ctx, items)) {
+		return
+	}
+
+	request := <-announce
+	assert.Equal(SdpMimeType, request.Header.Get("Content-Type"))
+	assert.NotEmpty(request.Header.Get("Content-Length"))
+	assert.Equal("rtsp://"+addr+"/live/trackID=0", items[0].URL.String())
+
+	if !assert.NoError(p.Setup(ctx)) {
+		return
+	}
+	assert.True(strings.HasSuffix(<-transport, ";mode=record"))
+
+	assert.ErrorIs(p.WriteRTP(0, []byte{0x80}), ErrNotRecording)
+
+	recordErrorCh := make(chan error, 1)
+	go func() {
+		recordErrorCh <- p.Record(ctx, nil)
+	}()
+
+	packet := []byte{0x80, 0x60, 0x00, 0x01}
+	for i := 0; i < 100; i++ {
+		if err = p.WriteRTP(0, packet); err != ErrNotRecording {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if !assert.NoError(err) {
+		return
+	}
+
+	buf := make([]byte, 64)
+	media.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := media.ReadFromUDP(buf)
+	if assert.NoError(err) {
+		assert.Equal(packet, buf[:n])
+	}
+
+	cancel()
+	assert.NoError(<-recordErrorCh)
+}
@@ -0,0 +1,295 @@
+package rtsp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Range units
+const (
+	RangeNPT   = "npt"
+	RangeClock = "clock"
+	RangeSMPTE = "smpte"
+	// RangeSMPTE25 is the SMPTE timecode with 25 frames per second
+	RangeSMPTE25 = "smpte-25"
+	// RangeSMPTE30Drop is the SMPTE timecode with 29.97 frames per second
+	RangeSMPTE30Drop = "smpte-30-drop"
+)
+
+const clockLayout = "20060102T150405.999Z"
+
+// Range is a value of the Range header
+// https://datatracker.ietf.org/doc/html/rfc2326#section-12.29
+type Range struct {
+	// Unit is one of the RangeNPT, RangeClock, RangeSMPTE,
+	// RangeSMPTE25 or RangeSMPTE30Drop
+	Unit string
+	// Start is the first position in the unit format
+	Start string
+	// End is the last position in the unit format. Empty for open range
+	End string
+}
+
+// NewRangeNPT makes range in the Normal Play Time.
+// End equal to 0 means the open range
+func NewRangeNPT(start, end time.Duration) Range {
+	r := Range{
+		Unit:  RangeNPT,
+		Start: formatNPT(start),
+	}
+
+	if end > 0 {
+		r.End = formatNPT(end)
+	}
+
+	return r
+}
+
+// NewRangeClock makes range in the absolute UTC time.
+// Zero end means the open range
+func NewRangeClock(start, end time.Time) Range {
+	r := Range{
+		Unit:  RangeClock,
+		Start: start.UTC().Format(clockLayout),
+	}
+
+	if !end.IsZero() {
+		r.End = end.UTC().Format(clockLayout)
+	}
+
+	return r
+}
+
+// NewRangeSMPTE makes range with SMPTE timecodes.
+// Timecode format is hours:minutes:seconds:frames.subframes
+func NewRangeSMPTE(start, end string) Range {
+	return Range{
+		Unit:  RangeSMPTE,
+		Start: start,
+		End:   end,
+	}
+}
+
+// ParseRange parses value of the Range header
+func ParseRange(value string) (Range, error) {
+	var r Range
+
+	// skip optional time parameter
+	value, _, _ = strings.Cut(value, ";")
+	value = strings.TrimSpace(value)
+
+	unit, position, ok := strings.Cut(value, "=")
+	if !ok {
+		return r, fmt.Errorf("invalid range %q", value)
+	}
+
+	r.Unit = strings.ToLower(strings.TrimSpace(unit))
+	switch r.Unit {
+	case RangeNPT, RangeClock, RangeSMPTE, RangeSMPTE25, RangeSMPTE30Drop:
+	default:
+		return r, fmt.Errorf("unsupported range unit %q", unit)
+	}
+
+	start, end, ok := strings.Cut(position, "-")
+	if !ok {
+		return r, fmt.Errorf("invalid range %q", value)
+	}
+
+	r.Start = strings.TrimSpace(start)
+	r.End = strings.TrimSpace(end)
+
+	return r, nil
+}
+
+// String returns value for the Range header
+func (r Range) String() string {
+	return r.Unit + "=" + r.Start + "-" + r.End
+}
+
+// NPT returns start and end positions for the Normal Play Time range.
+// Returns 0 for open or "now" positions
+func (r Range) NPT() (start, end time.Duration, err error) {
+	if r.Unit != RangeNPT {
+		return 0, 0, fmt.Errorf("range unit is %q", r.Unit)
+	}
+
+	if start, err = parseNPT(r.Start); err != nil {
+		return 0, 0, err
+	}
+
+	if end, err = parseNPT(r.End); err != nil {
+		return 0, 0, err
+	}
+
+	return start, end, nil
+}
+
+// Clock returns start and end positions for the absolute time range.
+// Returns zero time for open range
+func (r Range) Clock() (start, end time.Time, err error) {
+	if r.Unit != RangeClock {
+		return start, end, fmt.Errorf("range unit is %q", r.Unit)
+	}
+
+	if start, err = parseClock(r.Start); err != nil {
+		return start, end, err
+	}
+
+	if end, err = parseClock(r.End); err != nil {
+		return start, end, err
+	}
+
+	return start, end, nil
+}
+
+func formatNPT(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+func parseNPT(value string) (time.Duration, error) {
+	if value == "" || value == "now" {
+		return 0, nil
+	}
+
+	// npt-hhmmss = npt-hh ":" npt-mm ":" npt-ss [ "." *DIGIT ]
+	var hours, minutes int
+	if h, rest, ok := strings.Cut(value, ":"); ok {
+		m, s, ok := strings.Cut(rest, ":")
+		if !ok {
+			return 0, fmt.Errorf("invalid npt %q", value)
+		}
+
+		var err error
+		if hours, err = strconv.Atoi(h); err != nil {
+			return 0, fmt.Errorf("invalid npt %q", value)
+		}
+		if minutes, err = strconv.Atoi(m); err != nil {
+			return 0, fmt.Errorf("invalid npt %q", value)
+		}
+
+		value = s
+	}
+
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || seconds < 0 {
+		return 0, fmt.Errorf("invalid npt %q", value)
+	}
+
+	d := time.Duration(hours)*time.Hour +
+		time.Duration(minutes)*time.Minute +
+		time.Duration(seconds*float64(time.Second))
+
+	return d, nil
+}
+
+func parseClock(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	t, err := time.Parse(clockLayout, value)
+	if err != nil {
+		return t, fmt.Errorf("invalid clock %q", value)
+	}
+
+	return t, nil
+}
+
+// Scale is a value of the Scale header.
+// 1 is the normal play rate, 2 is the fast forward at twice the normal rate,
+// negative values is the reverse playback
+// https://datatracker.ietf.org/doc/html/rfc2326#section-12.34
+type Scale float64
+
+// ParseScale parses value of the Scale header
+func ParseScale(value string) (Scale, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || v == 0 {
+		return 0, fmt.Errorf("invalid scale %q", value)
+	}
+
+	return Scale(v), nil
+}
+
+// String returns value for the Scale header
+func (s Scale) String() string {
+	return strconv.FormatFloat(float64(s), 'f', -1, 64)
+}
+
+// Speed is a value of the Speed header.
+// Requests the server to deliver data at the given multiple of the normal
+// bandwidth, without changing the play rate
+// https://datatracker.ietf.org/doc/html/rfc2326#section-12.35
+type Speed float64
+
+// ParseSpeed parses value of the Speed header
+func ParseSpeed(value string) (Speed, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || v <= 0 {
+		return 0, fmt.Errorf("invalid speed %q", value)
+	}
+
+	return Speed(v), nil
+}
+
+// String returns value for the Speed header
+func (s Speed) String() string {
+	return strconv.FormatFloat(float64(s), 'f', -1, 64)
+}
+
+// RTPInfo is an item of the RTP-Info header.
+// Contains the stream position of the first packet after PLAY
+// https://datatracker.ietf.org/doc/html/rfc2326#section-12.33
+type RTPInfo struct {
+	// URL is the stream control URL
+	URL string
+	// Seq is the sequence number of the first packet
+	Seq uint16
+	// RTPTime is the RTP timestamp of the first packet
+	RTPTime uint32
+
+	HasSeq     bool
+	HasRTPTime bool
+}
+
+// ParseRTPInfo parses value of the RTP-Info header.
+// Items without url are skipped
+func ParseRTPInfo(value string) []*RTPInfo {
+	var result []*RTPInfo
+
+	for _, stream := range strings.Split(value, ",") {
+		info := &RTPInfo{}
+
+		for _, param := range strings.Split(stream, ";") {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok {
+				continue
+			}
+
+			switch strings.ToLower(key) {
+			case "url":
+				info.URL = strings.Trim(value, "\"")
+
+			case "seq":
+				if v, err := strconv.ParseUint(value, 10, 16); err == nil {
+					info.Seq = uint16(v)
+					info.HasSeq = true
+				}
+
+			case "rtptime":
+				if v, err := strconv.ParseUint(value, 10, 32); err == nil {
+					info.RTPTime = uint32(v)
+					info.HasRTPTime = true
+				}
+			}
+		}
+
+		if info.URL != "" {
+			result = append(result, info)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,142 @@
+package rtsp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRange_String(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("npt=0.000-", NewRangeNPT(0, 0).String())
+	assert.Equal("npt=10.500-20.000", NewRangeNPT(10500*time.Millisecond, 20*time.Second).String())
+
+	start := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	end := start.Add(90 * time.Minute)
+	assert.Equal("clock=20230102T030405Z-", NewRangeClock(start, time.Time{}).String())
+	assert.Equal("clock=20230102T030405Z-20230102T043405Z", NewRangeClock(start, end).String())
+
+	assert.Equal("smpte=10:07:00-10:07:33:05.01", NewRangeSMPTE("10:07:00", "10:07:33:05.01").String())
+}
+
+func TestRange_ParseRange(t *testing.T) {
+	t.Run("npt", func(t *testing.T) {
+		assert := assert.New(t)
+
+		r, err := ParseRange("npt=12.5-1:02:03.250")
+		if !assert.NoError(err) {
+			return
+		}
+		assert.Equal(RangeNPT, r.Unit)
+
+		start, end, err := r.NPT()
+		assert.NoError(err)
+		assert.Equal(12500*time.Millisecond, start)
+		assert.Equal(time.Hour+2*time.Minute+3250*time.Millisecond, end)
+	})
+
+	t.Run("npt now", func(t *testing.T) {
+		assert := assert.New(t)
+
+		r, err := ParseRange("npt=now-")
+		if !assert.NoError(err) {
+			return
+		}
+
+		start, end, err := r.NPT()
+		assert.NoError(err)
+		assert.Equal(time.Duration(0), start)
+		assert.Equal(time.Duration(0), end)
+	})
+
+	t.Run("clock with time", func(t *testing.T) {
+		assert := assert.New(t)
+
+		r, err := ParseRange("clock=19961108T143720.25Z-;time=19970123T143720Z")
+		if !assert.NoError(err) {
+			return
+		}
+
+		start, end, err := r.Clock()
+		assert.NoError(err)
+		assert.Equal(time.Date(1996, 11, 8, 14, 37, 20, 250000000, time.UTC), start)
+		assert.True(end.IsZero())
+	})
+
+	t.Run("smpte", func(t *testing.T) {
+		assert := assert.New(t)
+
+		r, err := ParseRange("smpte-25=10:07:00-")
+		if !assert.NoError(err) {
+			return
+		}
+		assert.Equal(Range{Unit: RangeSMPTE25, Start: "10:07:00"}, r)
+		assert.Equal("smpte-25=10:07:00-", r.String())
+
+		r, err = ParseRange("smpte-30-drop=10:07:00-10:07:33:05.01")
+		if !assert.NoError(err) {
+			return
+		}
+		assert.Equal(RangeSMPTE30Drop, r.Unit)
+		assert.Equal("smpte-30-drop=10:07:00-10:07:33:05.01", r.String())
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		assert := assert.New(t)
+
+		_, err := ParseRange("bytes=0-100")
+		assert.Error(err)
+
+		_, err = ParseRange("npt")
+		assert.Error(err)
+	})
+}
+
+func TestRange_Scale(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("-2", Scale(-2).String())
+	assert.Equal("0.5", Speed(0.5).String())
+
+	scale, err := ParseScale(" -0.5 ")
+	assert.NoError(err)
+	assert.Equal(Scale(-0.5), scale)
+
+	speed, err := ParseSpeed("2.0")
+	assert.NoError(err)
+	assert.Equal(Speed(2), speed)
+
+	_, err = ParseScale("0")
+	assert.Error(err)
+
+	_, err = ParseSpeed("-1")
+	assert.Error(err)
+}
+
+func TestRange_ParseRTPInfo(t *testing.T) {
+	assert := assert.New(t)
+
+	result := ParseRTPInfo(
+		"url=rtsp://foo.com/bar.avi/streamid=0;seq=45102;rtptime=12345678," +
+			"url=rtsp://foo.com/bar.avi/streamid=1;seq=30211",
+	)
+
+	expected := []*RTPInfo{
+		{
+			URL:        "rtsp://foo.com/bar.avi/streamid=0",
+			Seq:        45102,
+			RTPTime:    12345678,
+			HasSeq:     true,
+			HasRTPTime: true,
+		},
+		{
+			URL:    "rtsp://foo.com/bar.avi/streamid=1",
+			Seq:    30211,
+			HasSeq: true,
+		},
+	}
+
+	assert.Equal(expected, result)
+}
@@ -14,6 +14,7 @@ type Request struct {
 	Method string
 	URL    *url.URL
 	Header http.Header
+	Body   []byte
 }
 
 func parseRequestLine(line string) (method, requestURI, proto string, ok bool) {
@@ -67,15 +68,43 @@ func ReadRequest(r *bufio.Reader) (request *Request, err error) {
 	return request, nil
 }
 
+// ReadBody reads the body after request.
+func (r *Request) ReadBody(reader *bufio.Reader) (err error) {
+	r.Body, err = readBody(r.Header, reader)
+	return
+}
+
+// sanitizeURL returns URL without credentials
+func sanitizeURL(u *url.URL) string {
+	return stripCredentials(u).String()
+}
+
+// stripCredentials returns copy of URL without credentials
+func stripCredentials(u *url.URL) *url.URL {
+	v := *u
+	v.User = nil
+
+	return &v
+}
+
+// authHeader returns value for Authorization or Proxy-Authorization header
+func authHeader(auth Auth, request *Request, uri string) string {
+	if a, ok := auth.(AuthBody); ok {
+		return a.HeaderBody(request.Method, uri, request.Body)
+	}
+
+	return auth.Header(request.Method, uri)
+}
+
 // SendRequest sends RTSP/1.0 request to the server.
 func (c *Client) SendRequest(request *Request) error {
 	var err error
 
+	c.wlock.Lock()
+	defer c.wlock.Unlock()
+
 	// Ommit credentials from the request line
-	user := request.URL.User
-	request.URL.User = nil
-	uri := request.URL.String()
-	request.URL.User = user
+	uri := sanitizeURL(request.URL)
 
 	// Send the request
 	_, err = fmt.Fprintf(c.bw, "%s %s RTSP/1.0\r\n", request.Method, uri)
@@ -111,7 +140,19 @@ func (c *Client) SendRequest(request *Request) error {
 		_, err = fmt.Fprintf(
 			c.bw,
 			"Authorization: %s\r\n",
-			c.auth.Header(request.Method, uri),
+			authHeader(c.auth, request, uri),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Proxy-Authorization
+	if c.proxyAuth != nil {
+		_, err = fmt.Fprintf(
+			c.bw,
+			"Proxy-Authorization: %s\r\n",
+			authHeader(c.proxyAuth, request, uri),
 		)
 		if err != nil {
 			return err
@@ -126,10 +167,26 @@ func (c *Client) SendRequest(request *Request) error {
 		}
 	}
 
+	// Content-Length
+	if len(request.Body) != 0 {
+		_, err = fmt.Fprintf(c.bw, "Content-Length: %d\r\n", len(request.Body))
+		if err != nil {
+			return err
+		}
+	}
+
 	_, err = fmt.Fprint(c.bw, "\r\n")
 	if err != nil {
 		return err
 	}
 
+	// Body
+	if len(request.Body) != 0 {
+		_, err = c.bw.Write(request.Body)
+		if err != nil {
+			return err
+		}
+	}
+
 	return c.bw.Flush()
 }
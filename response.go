@@ -18,6 +18,152 @@ type Response struct {
 	Body       []byte
 }
 
+// RTSP status codes
+// https://datatracker.ietf.org/doc/html/rfc2326#section-7.1.1
+const (
+	StatusContinue = 100
+
+	StatusOK                = 200
+	StatusCreated           = 201
+	StatusLowOnStorageSpace = 250
+
+	StatusMultipleChoices  = 300
+	StatusMovedPermanently = 301
+	StatusMovedTemporarily = 302
+	StatusSeeOther         = 303
+	StatusUseProxy         = 305
+	// StatusTemporaryRedirect is not defined in RTSP/1.0, but used by some servers
+	StatusTemporaryRedirect = 307
+
+	StatusBadRequest                     = 400
+	StatusUnauthorized                   = 401
+	StatusPaymentRequired                = 402
+	StatusForbidden                      = 403
+	StatusNotFound                       = 404
+	StatusMethodNotAllowed               = 405
+	StatusNotAcceptable                  = 406
+	StatusProxyAuthRequired              = 407
+	StatusRequestTimeout                 = 408
+	StatusGone                           = 410
+	StatusLengthRequired                 = 411
+	StatusPreconditionFailed             = 412
+	StatusRequestEntityTooLarge          = 413
+	StatusRequestURITooLong              = 414
+	StatusUnsupportedMediaType           = 415
+	StatusParameterNotUnderstood         = 451
+	StatusConferenceNotFound             = 452
+	StatusNotEnoughBandwidth             = 453
+	StatusSessionNotFound                = 454
+	StatusMethodNotValidInThisState      = 455
+	StatusHeaderFieldNotValidForResource = 456
+	StatusInvalidRange                   = 457
+	StatusParameterIsReadOnly            = 458
+	StatusAggregateOperationNotAllowed   = 459
+	StatusOnlyAggregateOperationAllowed  = 460
+	StatusUnsupportedTransport           = 461
+	StatusDestinationUnreachable         = 462
+
+	StatusInternalServerError     = 500
+	StatusNotImplemented          = 501
+	StatusBadGateway              = 502
+	StatusServiceUnavailable      = 503
+	StatusGatewayTimeout          = 504
+	StatusRTSPVersionNotSupported = 505
+	StatusOptionNotSupported      = 551
+)
+
+var statusText = map[int]string{
+	StatusContinue:                       "Continue",
+	StatusOK:                             "OK",
+	StatusCreated:                        "Created",
+	StatusLowOnStorageSpace:              "Low on Storage Space",
+	StatusMultipleChoices:                "Multiple Choices",
+	StatusMovedPermanently:               "Moved Permanently",
+	StatusMovedTemporarily:               "Moved Temporarily",
+	StatusSeeOther:                       "See Other",
+	StatusUseProxy:                       "Use Proxy",
+	StatusTemporaryRedirect:              "Temporary Redirect",
+	StatusBadRequest:                     "Bad Request",
+	StatusUnauthorized:                   "Unauthorized",
+	StatusPaymentRequired:                "Payment Required",
+	StatusForbidden:                      "Forbidden",
+	StatusNotFound:                       "Not Found",
+	StatusMethodNotAllowed:               "Method Not Allowed",
+	StatusNotAcceptable:                  "Not Acceptable",
+	StatusProxyAuthRequired:              "Proxy Authentication Required",
+	StatusRequestTimeout:                 "Request Timeout",
+	StatusGone:                           "Gone",
+	StatusLengthRequired:                 "Length Required",
+	StatusPreconditionFailed:             "Precondition Failed",
+	StatusRequestEntityTooLarge:          "Request Entity Too Large",
+	StatusRequestURITooLong:              "Request-URI Too Long",
+	StatusUnsupportedMediaType:           "Unsupported Media Type",
+	StatusParameterNotUnderstood:         "Parameter Not Understood",
+	StatusConferenceNotFound:             "Conference Not Found",
+	StatusNotEnoughBandwidth:             "Not Enough Bandwidth",
+	StatusSessionNotFound:                "Session Not Found",
+	StatusMethodNotValidInThisState:      "Method Not Valid in This State",
+	StatusHeaderFieldNotValidForResource: "Header Field Not Valid for Resource",
+	StatusInvalidRange:                   "Invalid Range",
+	StatusParameterIsReadOnly:            "Parameter Is Read-Only",
+	StatusAggregateOperationNotAllowed:   "Aggregate Operation Not Allowed",
+	StatusOnlyAggregateOperationAllowed:  "Only Aggregate Operation Allowed",
+	StatusUnsupportedTransport:           "Unsupported Transport",
+	StatusDestinationUnreachable:         "Destination Unreachable",
+	StatusInternalServerError:            "Internal Server Error",
+	StatusNotImplemented:                 "Not Implemented",
+	StatusBadGateway:                     "Bad Gateway",
+	StatusServiceUnavailable:             "Service Unavailable",
+	StatusGatewayTimeout:                 "Gateway Timeout",
+	StatusRTSPVersionNotSupported:        "RTSP Version Not Supported",
+	StatusOptionNotSupported:             "Option Not Supported",
+}
+
+// StatusError is returned when the server responds with an error status
+type StatusError struct {
+	// Code is the response status code
+	Code int
+	// Reason is the response reason phrase
+	Reason string
+	// Method is the request method
+	Method string
+	// URL is the request URL without credentials
+	URL string
+}
+
+func newStatusError(request *Request, response *Response) *StatusError {
+	reason := strings.TrimPrefix(response.Status, strconv.Itoa(response.StatusCode))
+	if reason = strings.TrimSpace(reason); reason == "" {
+		reason = statusText[response.StatusCode]
+	}
+
+	return &StatusError{
+		Code:   response.StatusCode,
+		Reason: reason,
+		Method: request.Method,
+		URL:    sanitizeURL(request.URL),
+	}
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s %s: %d %s", e.Method, e.URL, e.Code, e.Reason)
+}
+
+// NewResponse makes a new response with status code and empty header
+func NewResponse(code int) *Response {
+	status := strconv.Itoa(code)
+	if text, ok := statusText[code]; ok {
+		status += " " + text
+	}
+
+	return &Response{
+		Proto:      "RTSP/1.0",
+		StatusCode: code,
+		Status:     status,
+		Header:     http.Header{},
+	}
+}
+
 func parseResponseLine(line string) (proto, status string, code int, ok bool) {
 	proto, status, ok = strings.Cut(line, " ")
 	if !ok {
@@ -72,32 +218,78 @@ func ReadResponse(reader *bufio.Reader) (response *Response, err error) {
 }
 
 // ReadBody reads the body after response.
-func (r *Response) ReadBody(reader *bufio.Reader) error {
-	v := r.Header.Get("content-length")
+func (r *Response) ReadBody(reader *bufio.Reader) (err error) {
+	r.Body, err = readBody(r.Header, reader)
+	return
+}
+
+func readBody(header http.Header, reader *bufio.Reader) ([]byte, error) {
+	v := header.Get("content-length")
 	if v == "" {
-		return nil
+		return nil, nil
 	}
 
 	contentLength, err := strconv.Atoi(v)
 	if err != nil {
-		return fmt.Errorf("invalid content-length %q", v)
+		return nil, fmt.Errorf("invalid content-length %q", v)
 	}
 
 	if contentLength == 0 {
-		return nil
+		return nil, nil
 	}
 
 	if contentLength > (64 * 1024) {
-		return fmt.Errorf("content-length too large %d", contentLength)
+		return nil, fmt.Errorf("content-length too large %d", contentLength)
 	}
 
-	r.Body = make([]byte, contentLength)
-	if _, err = io.ReadFull(reader, r.Body); err != nil {
+	body := make([]byte, contentLength)
+	if _, err = io.ReadFull(reader, body); err != nil {
 		if err == io.EOF {
 			err = io.ErrUnexpectedEOF
 		}
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// WriteResponse writes a response to the client.
+// Content-Length header is defined by the response body.
+func WriteResponse(writer *bufio.Writer, response *Response) error {
+	var err error
+
+	proto := response.Proto
+	if proto == "" {
+		proto = "RTSP/1.0"
+	}
+
+	_, err = fmt.Fprintf(writer, "%s %s\r\n", proto, response.Status)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	if response.Header == nil {
+		response.Header = http.Header{}
+	}
+
+	response.Header.Del("Content-Length")
+	if len(response.Body) != 0 {
+		response.Header.Set("Content-Length", strconv.Itoa(len(response.Body)))
+	}
+
+	if err = response.Header.Write(writer); err != nil {
+		return err
+	}
+
+	if _, err = fmt.Fprint(writer, "\r\n"); err != nil {
+		return err
+	}
+
+	if len(response.Body) != 0 {
+		if _, err = writer.Write(response.Body); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
 }
@@ -0,0 +1,466 @@
+package rtsp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidRTCPPacket = fmt.Errorf("invalid rtcp packet")
+
+// RTCP packet types
+// https://datatracker.ietf.org/doc/html/rfc3550#section-12.1
+const (
+	RTCPTypeSR    = 200
+	RTCPTypeRR    = 201
+	RTCPTypeSDES  = 202
+	RTCPTypeBYE   = 203
+	RTCPTypeAPP   = 204
+	RTCPTypeRTPFB = 205
+	RTCPTypePSFB  = 206
+)
+
+// SDES item types
+// https://datatracker.ietf.org/doc/html/rfc3550#section-6.5
+const (
+	SDESEnd   = 0
+	SDESCNAME = 1
+	SDESNAME  = 2
+	SDESEMAIL = 3
+	SDESPHONE = 4
+	SDESLOC   = 5
+	SDESTOOL  = 6
+	SDESNOTE  = 7
+	SDESPRIV  = 8
+)
+
+const rtcpHeaderSize = 4
+
+// RTCPPacket is the packet in the RTCP compound packet
+type RTCPPacket interface {
+	// PacketType returns the RTCP packet type
+	PacketType() uint8
+}
+
+// RTCPReceptionReport is the reception report block for one source
+// https://datatracker.ietf.org/doc/html/rfc3550#section-6.4.1
+type RTCPReceptionReport struct {
+	SSRC uint32
+	// FractionLost is the fraction of packets lost since the last report
+	// as a fixed point number with the binary point at the left edge
+	FractionLost uint8
+	// PacketsLost is the cumulative number of packets lost, 24-bit signed
+	PacketsLost int32
+	// HighestSequence is the extended highest sequence number received
+	HighestSequence uint32
+	// Jitter is the interarrival jitter in timestamp units
+	Jitter uint32
+	// LastSR is the middle 32 bits of NTP timestamp from the last SR
+	LastSR uint32
+	// DelaySinceLastSR is the delay since the last SR in 1/65536 seconds
+	DelaySinceLastSR uint32
+}
+
+// RTCPSenderReport is the sender report
+// https://datatracker.ietf.org/doc/html/rfc3550#section-6.4.1
+type RTCPSenderReport struct {
+	SSRC uint32
+	// NTPTime is the wallclock time in NTP format
+	NTPTime     uint64
+	RTPTime     uint32
+	PacketCount uint32
+	OctetCount  uint32
+	Reports     []RTCPReceptionReport
+}
+
+func (p *RTCPSenderReport) PacketType() uint8 { return RTCPTypeSR }
+
+// Time returns the wallclock time of the report
+func (p *RTCPSenderReport) Time() time.Time {
+	return ntpToTime(p.NTPTime)
+}
+
+// RTCPReceiverReport is the receiver report
+// https://datatracker.ietf.org/doc/html/rfc3550#section-6.4.2
+type RTCPReceiverReport struct {
+	SSRC    uint32
+	Reports []RTCPReceptionReport
+}
+
+func (p *RTCPReceiverReport) PacketType() uint8 { return RTCPTypeRR }
+
+// Marshal returns the packet data
+func (p *RTCPReceiverReport) Marshal() ([]byte, error) {
+	if len(p.Reports) > 31 {
+		return nil, fmt.Errorf("too many report blocks %d", len(p.Reports))
+	}
+
+	buf := make([]byte, rtcpHeaderSize+4+len(p.Reports)*24)
+	writeRTCPHeader(buf, uint8(len(p.Reports)), RTCPTypeRR)
+	binary.BigEndian.PutUint32(buf[4:], p.SSRC)
+
+	for i, r := range p.Reports {
+		b := buf[8+i*24:]
+		binary.BigEndian.PutUint32(b[0:], r.SSRC)
+		binary.BigEndian.PutUint32(b[4:], uint32(r.PacketsLost)&0xFFFFFF)
+		b[4] = r.FractionLost
+		binary.BigEndian.PutUint32(b[8:], r.HighestSequence)
+		binary.BigEndian.PutUint32(b[12:], r.Jitter)
+		binary.BigEndian.PutUint32(b[16:], r.LastSR)
+		binary.BigEndian.PutUint32(b[20:], r.DelaySinceLastSR)
+	}
+
+	return buf, nil
+}
+
+// RTCPSourceDescription is the source description
+// https://datatracker.ietf.org/doc/html/rfc3550#section-6.5
+type RTCPSourceDescription struct {
+	Chunks []RTCPSDESChunk
+}
+
+func (p *RTCPSourceDescription) PacketType() uint8 { return RTCPTypeSDES }
+
+// Marshal returns the packet data
+func (p *RTCPSourceDescription) Marshal() ([]byte, error) {
+	if len(p.Chunks) > 31 {
+		return nil, fmt.Errorf("too many sdes chunks %d", len(p.Chunks))
+	}
+
+	size := rtcpHeaderSize
+	for _, chunk := range p.Chunks {
+		chunkSize := 4
+		for _, item := range chunk.Items {
+			if len(item.Text) > 255 {
+				return nil, fmt.Errorf("sdes item too long %d", len(item.Text))
+			}
+			chunkSize += 2 + len(item.Text)
+		}
+		// null item and padding to 32 bits
+		size += (chunkSize + 4) &^ 3
+	}
+
+	buf := make([]byte, size)
+	writeRTCPHeader(buf, uint8(len(p.Chunks)), RTCPTypeSDES)
+
+	offset := rtcpHeaderSize
+	for _, chunk := range p.Chunks {
+		binary.BigEndian.PutUint32(buf[offset:], chunk.Source)
+		offset += 4
+
+		for _, item := range chunk.Items {
+			buf[offset] = item.Type
+			buf[offset+1] = uint8(len(item.Text))
+			offset += 2 + copy(buf[offset+2:], item.Text)
+		}
+
+		offset = (offset + 4) &^ 3
+	}
+
+	return buf, nil
+}
+
+// RTCPSDESChunk is the list of items for one source
+type RTCPSDESChunk struct {
+	Source uint32
+	Items  []RTCPSDESItem
+}
+
+// RTCPSDESItem is the source description item, like SDESCNAME
+type RTCPSDESItem struct {
+	Type uint8
+	Text string
+}
+
+// RTCPGoodbye is the BYE packet. Sources are no longer active
+// https://datatracker.ietf.org/doc/html/rfc3550#section-6.6
+type RTCPGoodbye struct {
+	Sources []uint32
+	Reason  string
+}
+
+func (p *RTCPGoodbye) PacketType() uint8 { return RTCPTypeBYE }
+
+// RTCPApp is the application-defined packet
+// https://datatracker.ietf.org/doc/html/rfc3550#section-6.7
+type RTCPApp struct {
+	SubType uint8
+	SSRC    uint32
+	Name    string
+	Data    []byte
+}
+
+func (p *RTCPApp) PacketType() uint8 { return RTCPTypeAPP }
+
+// RTCPFeedback is the transport layer (RTPFB) or payload-specific (PSFB)
+// feedback message. FCI is the feedback control information
+// https://datatracker.ietf.org/doc/html/rfc4585#section-6.1
+type RTCPFeedback struct {
+	// Type is RTCPTypeRTPFB or RTCPTypePSFB
+	Type uint8
+	// Format is the feedback message type, like 1 for Generic NACK or PLI
+	Format     uint8
+	SenderSSRC uint32
+	MediaSSRC  uint32
+	FCI        []byte
+}
+
+func (p *RTCPFeedback) PacketType() uint8 { return p.Type }
+
+// RTCPUnknown is the packet with unsupported type
+type RTCPUnknown struct {
+	Type uint8
+	// Count is the 5-bit value from the header
+	Count   uint8
+	Payload []byte
+}
+
+func (p *RTCPUnknown) PacketType() uint8 { return p.Type }
+
+// ParseRTCP parses the RTCP compound packet.
+// Byte slices in the result refer to data
+func ParseRTCP(data []byte) ([]RTCPPacket, error) {
+	var result []RTCPPacket
+
+	for len(data) != 0 {
+		if len(data) < rtcpHeaderSize {
+			return nil, fmt.Errorf("%w: header too short", ErrInvalidRTCPPacket)
+		}
+
+		if version := data[0] >> 6; version != RTPVersion {
+			return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidRTCPPacket, version)
+		}
+
+		count := data[0] & 0x1F
+		packetType := data[1]
+		size := (int(binary.BigEndian.Uint16(data[2:4])) + 1) * 4
+
+		if len(data) < size {
+			return nil, fmt.Errorf("%w: packet too short", ErrInvalidRTCPPacket)
+		}
+
+		payload := data[rtcpHeaderSize:size]
+
+		if data[0]&0x20 != 0 {
+			padding := int(data[size-1])
+			if padding == 0 || padding > len(payload) {
+				return nil, fmt.Errorf("%w: invalid padding %d", ErrInvalidRTCPPacket, padding)
+			}
+			payload = payload[:len(payload)-padding]
+		}
+
+		packet, err := parseRTCPPacket(packetType, count, payload)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, packet)
+		data = data[size:]
+	}
+
+	return result, nil
+}
+
+func parseRTCPPacket(packetType, count uint8, payload []byte) (RTCPPacket, error) {
+	switch packetType {
+	case RTCPTypeSR:
+		if len(payload) < 24 {
+			return nil, fmt.Errorf("%w: sender report too short", ErrInvalidRTCPPacket)
+		}
+
+		reports, err := parseRTCPReports(count, payload[24:])
+		if err != nil {
+			return nil, err
+		}
+
+		return &RTCPSenderReport{
+			SSRC:        binary.BigEndian.Uint32(payload[0:]),
+			NTPTime:     binary.BigEndian.Uint64(payload[4:]),
+			RTPTime:     binary.BigEndian.Uint32(payload[12:]),
+			PacketCount: binary.BigEndian.Uint32(payload[16:]),
+			OctetCount:  binary.BigEndian.Uint32(payload[20:]),
+			Reports:     reports,
+		}, nil
+
+	case RTCPTypeRR:
+		if len(payload) < 4 {
+			return nil, fmt.Errorf("%w: receiver report too short", ErrInvalidRTCPPacket)
+		}
+
+		reports, err := parseRTCPReports(count, payload[4:])
+		if err != nil {
+			return nil, err
+		}
+
+		return &RTCPReceiverReport{
+			SSRC:    binary.BigEndian.Uint32(payload[0:]),
+			Reports: reports,
+		}, nil
+
+	case RTCPTypeSDES:
+		return parseRTCPSourceDescription(count, payload)
+
+	case RTCPTypeBYE:
+		if len(payload) < int(count)*4 {
+			return nil, fmt.Errorf("%w: bye too short", ErrInvalidRTCPPacket)
+		}
+
+		p := &RTCPGoodbye{}
+		for i := 0; i < int(count); i++ {
+			p.Sources = append(p.Sources, binary.BigEndian.Uint32(payload[i*4:]))
+		}
+
+		if rest := payload[int(count)*4:]; len(rest) != 0 {
+			size := int(rest[0])
+			if len(rest) < 1+size {
+				return nil, fmt.Errorf("%w: bye reason too short", ErrInvalidRTCPPacket)
+			}
+			p.Reason = string(rest[1 : 1+size])
+		}
+
+		return p, nil
+
+	case RTCPTypeAPP:
+		if len(payload) < 8 {
+			return nil, fmt.Errorf("%w: app too short", ErrInvalidRTCPPacket)
+		}
+
+		return &RTCPApp{
+			SubType: count,
+			SSRC:    binary.BigEndian.Uint32(payload[0:]),
+			Name:    string(payload[4:8]),
+			Data:    payload[8:],
+		}, nil
+
+	case RTCPTypeRTPFB, RTCPTypePSFB:
+		if len(payload) < 8 {
+			return nil, fmt.Errorf("%w: feedback too short", ErrInvalidRTCPPacket)
+		}
+
+		return &RTCPFeedback{
+			Type:       packetType,
+			Format:     count,
+			SenderSSRC: binary.BigEndian.Uint32(payload[0:]),
+			MediaSSRC:  binary.BigEndian.Uint32(payload[4:]),
+			FCI:        payload[8:],
+		}, nil
+
+	default:
+		return &RTCPUnknown{
+			Type:    packetType,
+			Count:   count,
+			Payload: payload,
+		}, nil
+	}
+}
+
+// parseRTCPReports parses reception report blocks
+func parseRTCPReports(count uint8, data []byte) ([]RTCPReceptionReport, error) {
+	if len(data) < int(count)*24 {
+		return nil, fmt.Errorf("%w: report blocks too short", ErrInvalidRTCPPacket)
+	}
+
+	var reports []RTCPReceptionReport
+
+	for i := 0; i < int(count); i++ {
+		b := data[i*24:]
+
+		// 24-bit signed value
+		lost := int32(binary.BigEndian.Uint32(b[4:8])<<8) >> 8
+
+		reports = append(reports, RTCPReceptionReport{
+			SSRC:             binary.BigEndian.Uint32(b[0:]),
+			FractionLost:     b[4],
+			PacketsLost:      lost,
+			HighestSequence:  binary.BigEndian.Uint32(b[8:]),
+			Jitter:           binary.BigEndian.Uint32(b[12:]),
+			LastSR:           binary.BigEndian.Uint32(b[16:]),
+			DelaySinceLastSR: binary.BigEndian.Uint32(b[20:]),
+		})
+	}
+
+	return reports, nil
+}
+
+// parseRTCPSourceDescription parses SDES chunks.
+// Each chunk is terminated with null items and aligned to 32 bits
+func parseRTCPSourceDescription(count uint8, data []byte) (*RTCPSourceDescription, error) {
+	p := &RTCPSourceDescription{}
+	offset := 0
+
+	for i := 0; i < int(count); i++ {
+		if len(data) < offset+4 {
+			return nil, fmt.Errorf("%w: sdes chunk too short", ErrInvalidRTCPPacket)
+		}
+
+		chunk := RTCPSDESChunk{
+			Source: binary.BigEndian.Uint32(data[offset:]),
+		}
+		offset += 4
+
+		for {
+			if offset >= len(data) {
+				return nil, fmt.Errorf("%w: sdes items not terminated", ErrInvalidRTCPPacket)
+			}
+
+			itemType := data[offset]
+			if itemType == SDESEnd {
+				// skip null octets to the next 32-bit boundary
+				offset = (offset + 4) &^ 3
+				break
+			}
+
+			if len(data) < offset+2 || len(data) < offset+2+int(data[offset+1]) {
+				return nil, fmt.Errorf("%w: sdes item too short", ErrInvalidRTCPPacket)
+			}
+
+			size := int(data[offset+1])
+			chunk.Items = append(chunk.Items, RTCPSDESItem{
+				Type: itemType,
+				Text: string(data[offset+2 : offset+2+size]),
+			})
+			offset += 2 + size
+		}
+
+		p.Chunks = append(p.Chunks, chunk)
+	}
+
+	return p, nil
+}
+
+// writeRTCPHeader writes the packet header.
+// Length is defined by the buffer size
+func writeRTCPHeader(buf []byte, count, packetType uint8) {
+	buf[0] = RTPVersion<<6 | count
+	buf[1] = packetType
+	binary.BigEndian.PutUint16(buf[2:], uint16(len(buf)/4-1))
+}
+
+// hasRTCPBye checks if the compound packet contains BYE
+func hasRTCPBye(data []byte) bool {
+	for len(data) >= rtcpHeaderSize {
+		if data[1] == RTCPTypeBYE {
+			return true
+		}
+
+		size := (int(binary.BigEndian.Uint16(data[2:4])) + 1) * 4
+		if size > len(data) {
+			return false
+		}
+		data = data[size:]
+	}
+
+	return false
+}
+
+// ntpEpochOffset is the number of seconds from 1900 to 1970
+const ntpEpochOffset = 2208988800
+
+// ntpToTime converts 64-bit NTP timestamp to time
+func ntpToTime(ntp uint64) time.Time {
+	seconds := int64(ntp>>32) - ntpEpochOffset
+	fraction := int64(ntp & 0xFFFFFFFF)
+	nanoseconds := (fraction * int64(time.Second)) >> 32
+
+	return time.Unix(seconds, nanoseconds)
+}
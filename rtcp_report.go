@@ -0,0 +1,444 @@
+package rtsp
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	mrand "math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// rtcpMinInterval is the minimal interval between RTCP reports
+	// https://datatracker.ietf.org/doc/html/rfc3550#section-6.2
+	rtcpMinInterval = 5 * time.Second
+	// rtcpCompensation compensates for timer reconsideration
+	// https://datatracker.ietf.org/doc/html/rfc3550#section-6.3.1
+	rtcpCompensation = 2.71828 - 1.5
+
+	defaultClockRate = 90000
+
+	// sequence number validation
+	// https://datatracker.ietf.org/doc/html/rfc3550#appendix-A.1
+	rtpMaxDropout    = 3000
+	rtpMaxMisorder   = 100
+	rtpMinSequential = 2
+	rtpSeqMod        = 1 << 16
+)
+
+// rtpSource is the reception statistics for one synchronization source
+type rtpSource struct {
+	ssrc uint32
+
+	maxSeq    uint16
+	cycles    uint32
+	baseSeq   uint32
+	badSeq    uint32
+	probation int
+
+	received      uint32
+	expectedPrior uint32
+	receivedPrior uint32
+
+	// transit is the relative transit time of the previous packet
+	transit    int32
+	hasTransit bool
+	// jitter is the interarrival jitter in timestamp units
+	jitter float64
+
+	// lastSR is the middle 32 bits of NTP timestamp from the last SR
+	lastSR     uint32
+	lastSRTime time.Time
+}
+
+func newRTPSource(ssrc uint32, seq uint16) *rtpSource {
+	s := &rtpSource{
+		ssrc:      ssrc,
+		probation: rtpMinSequential,
+	}
+	s.initSeq(seq)
+	s.maxSeq = seq - 1
+
+	return s
+}
+
+func (s *rtpSource) initSeq(seq uint16) {
+	s.baseSeq = uint32(seq)
+	s.maxSeq = seq
+	s.badSeq = rtpSeqMod + 1
+	s.cycles = 0
+	s.received = 0
+	s.receivedPrior = 0
+	s.expectedPrior = 0
+}
+
+// updateSeq validates the sequence number.
+// Returns false if packet should not be counted
+// https://datatracker.ietf.org/doc/html/rfc3550#appendix-A.1
+func (s *rtpSource) updateSeq(seq uint16) bool {
+	udelta := seq - s.maxSeq
+
+	if s.probation > 0 {
+		// packet is in sequence
+		if seq == s.maxSeq+1 {
+			s.probation--
+			s.maxSeq = seq
+			if s.probation == 0 {
+				s.initSeq(seq)
+				s.received++
+				return true
+			}
+		} else {
+			s.probation = rtpMinSequential - 1
+			s.maxSeq = seq
+		}
+		return false
+	}
+
+	switch {
+	case udelta < rtpMaxDropout:
+		// in order, with permissible gap
+		if seq < s.maxSeq {
+			// sequence number wrapped
+			s.cycles += rtpSeqMod
+		}
+		s.maxSeq = seq
+	case int(udelta) <= rtpSeqMod-rtpMaxMisorder:
+		// the sequence number made a very large jump
+		if uint32(seq) == s.badSeq {
+			// two sequential packets, assume that the other side
+			// restarted without telling us
+			s.initSeq(seq)
+		} else {
+			s.badSeq = (uint32(seq) + 1) & (rtpSeqMod - 1)
+			return false
+		}
+	default:
+		// duplicate or reordered packet
+	}
+
+	s.received++
+
+	return true
+}
+
+// updateJitter updates the interarrival jitter.
+// arrival is the packet arrival time in timestamp units
+// https://datatracker.ietf.org/doc/html/rfc3550#appendix-A.8
+func (s *rtpSource) updateJitter(arrival, timestamp uint32) {
+	transit := int32(arrival - timestamp)
+
+	if s.hasTransit {
+		d := float64(transit - s.transit)
+		if d < 0 {
+			d = -d
+		}
+		s.jitter += (d - s.jitter) / 16
+	}
+
+	s.transit = transit
+	s.hasTransit = true
+}
+
+// report returns the reception report block and resets interval counters
+// https://datatracker.ietf.org/doc/html/rfc3550#appendix-A.3
+func (s *rtpSource) report(now time.Time) RTCPReceptionReport {
+	extendedMax := s.cycles + uint32(s.maxSeq)
+	expected := extendedMax - s.baseSeq + 1
+
+	lost := int64(expected) - int64(s.received)
+	if lost > 0x7FFFFF {
+		lost = 0x7FFFFF
+	} else if lost < -0x800000 {
+		lost = -0x800000
+	}
+
+	expectedInterval := expected - s.expectedPrior
+	s.expectedPrior = expected
+	receivedInterval := s.received - s.receivedPrior
+	s.receivedPrior = s.received
+
+	var fraction uint8
+	lostInterval := int64(expectedInterval) - int64(receivedInterval)
+	if expectedInterval != 0 && lostInterval > 0 {
+		fraction = uint8((lostInterval << 8) / int64(expectedInterval))
+	}
+
+	r := RTCPReceptionReport{
+		SSRC:            s.ssrc,
+		FractionLost:    fraction,
+		PacketsLost:     int32(lost),
+		HighestSequence: extendedMax,
+		Jitter:          uint32(s.jitter),
+		LastSR:          s.lastSR,
+	}
+
+	if !s.lastSRTime.IsZero() {
+		r.DelaySinceLastSR = delaySinceLastSR(now.Sub(s.lastSRTime))
+	}
+
+	return r
+}
+
+// delaySinceLastSR returns delay in units of 1/65536 seconds.
+// Limited with the maximum 32-bit value
+func delaySinceLastSR(delay time.Duration) uint32 {
+	if delay <= 0 {
+		return 0
+	}
+
+	seconds := int64(delay / time.Second)
+	if seconds > 0xFFFF {
+		return 0xFFFFFFFF
+	}
+
+	fraction := int64(delay%time.Second) << 16 / int64(time.Second)
+
+	return uint32(seconds<<16 + fraction)
+}
+
+// rtcpMedia is the reception statistics for one media stream
+type rtcpMedia struct {
+	clockRate int
+	sources   map[uint32]*rtpSource
+}
+
+// rtcpReporter collects reception statistics
+// and sends RTCP receiver reports with SDES CNAME
+// https://datatracker.ietf.org/doc/html/rfc3550#section-6.4.2
+type rtcpReporter struct {
+	ssrc  uint32
+	cname string
+	start time.Time
+	// interval is the minimal interval between reports
+	interval time.Duration
+
+	lock   sync.Mutex
+	medias map[int]*rtcpMedia
+}
+
+func newRTCPReporter() *rtcpReporter {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+
+	return &rtcpReporter{
+		ssrc: binary.BigEndian.Uint32(b[:4]),
+		// random CNAME for short-term persistent identifier
+		// https://datatracker.ietf.org/doc/html/rfc7022
+		cname:    base64.RawStdEncoding.EncodeToString(b[4:]),
+		start:    time.Now(),
+		interval: rtcpMinInterval,
+		medias:   make(map[int]*rtcpMedia),
+	}
+}
+
+func (r *rtcpReporter) getMedia(mediaID int) *rtcpMedia {
+	m := r.medias[mediaID]
+	if m == nil {
+		m = &rtcpMedia{
+			clockRate: defaultClockRate,
+			sources:   make(map[uint32]*rtpSource),
+		}
+		r.medias[mediaID] = m
+	}
+
+	return m
+}
+
+// setClockRate defines RTP clock rate for the media
+func (r *rtcpReporter) setClockRate(mediaID, clockRate int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if clockRate > 0 {
+		r.getMedia(mediaID).clockRate = clockRate
+	}
+}
+
+// onRTP updates statistics with received RTP packet
+func (r *rtcpReporter) onRTP(mediaID int, packet []byte, now time.Time) {
+	if len(packet) < rtpHeaderSize || packet[0]>>6 != RTPVersion {
+		return
+	}
+
+	seq := binary.BigEndian.Uint16(packet[2:4])
+	timestamp := binary.BigEndian.Uint32(packet[4:8])
+	ssrc := binary.BigEndian.Uint32(packet[8:12])
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	m := r.getMedia(mediaID)
+
+	s := m.sources[ssrc]
+	if s == nil {
+		s = newRTPSource(ssrc, seq)
+		m.sources[ssrc] = s
+	}
+
+	if s.updateSeq(seq) {
+		// arrival time in timestamp units, wraps like RTP timestamp
+		elapsed := now.Sub(r.start)
+		seconds, fraction := int64(elapsed/time.Second), int64(elapsed%time.Second)
+		rate := int64(m.clockRate)
+		arrival := uint32(seconds*rate + fraction*rate/int64(time.Second))
+		s.updateJitter(arrival, timestamp)
+	}
+}
+
+// onRTCP saves time of the sender reports
+func (r *rtcpReporter) onRTCP(mediaID int, packet []byte, now time.Time) {
+	packets, err := ParseRTCP(packet)
+	if err != nil {
+		return
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	m := r.getMedia(mediaID)
+
+	for _, p := range packets {
+		sr, ok := p.(*RTCPSenderReport)
+		if !ok {
+			continue
+		}
+
+		if s := m.sources[sr.SSRC]; s != nil {
+			s.lastSR = uint32(sr.NTPTime >> 16)
+			s.lastSRTime = now
+		}
+	}
+}
+
+// report returns the compound packet with RR and SDES CNAME.
+// Returns nil if no packets received for the media
+func (r *rtcpReporter) report(mediaID int, now time.Time) []byte {
+	r.lock.Lock()
+	m := r.medias[mediaID]
+	rr := &RTCPReceiverReport{SSRC: r.ssrc}
+	if m != nil {
+		for _, s := range m.sources {
+			if s.probation > 0 || len(rr.Reports) == 31 {
+				continue
+			}
+			rr.Reports = append(rr.Reports, s.report(now))
+		}
+	}
+	r.lock.Unlock()
+
+	if len(rr.Reports) == 0 {
+		return nil
+	}
+
+	sdes := &RTCPSourceDescription{
+		Chunks: []RTCPSDESChunk{
+			{
+				Source: r.ssrc,
+				Items:  []RTCPSDESItem{{Type: SDESCNAME, Text: r.cname}},
+			},
+		},
+	}
+
+	packet, _ := rr.Marshal()
+	b, _ := sdes.Marshal()
+
+	return append(packet, b...)
+}
+
+// nextInterval returns randomized interval to the next report.
+// Session bandwidth is not known, so the minimal interval is used
+// https://datatracker.ietf.org/doc/html/rfc3550#section-6.3.1
+func (r *rtcpReporter) nextInterval(initial bool) time.Duration {
+	t := float64(r.interval)
+	if initial {
+		t /= 2
+	}
+
+	return time.Duration(t * (mrand.Float64() + 0.5) / rtcpCompensation)
+}
+
+// run sends reports for all medias until done is closed
+func (r *rtcpReporter) run(done <-chan struct{}, write func(mediaID int, packet []byte) error) {
+	timer := time.NewTimer(r.nextInterval(true))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-timer.C:
+		}
+
+		now := time.Now()
+
+		r.lock.Lock()
+		mediaIDs := make([]int, 0, len(r.medias))
+		for mediaID := range r.medias {
+			mediaIDs = append(mediaIDs, mediaID)
+		}
+		r.lock.Unlock()
+
+		for _, mediaID := range mediaIDs {
+			if packet := r.report(mediaID, now); packet != nil {
+				_ = write(mediaID, packet)
+			}
+		}
+
+		timer.Reset(r.nextInterval(false))
+	}
+}
+
+// reportWatch is the media handler to collect reception statistics
+type reportWatch struct {
+	MediaHandler
+	reporter *rtcpReporter
+}
+
+func (w *reportWatch) OnRTP(mediaID int, packet []byte) {
+	w.reporter.onRTP(mediaID, packet, time.Now())
+	w.MediaHandler.OnRTP(mediaID, packet)
+}
+
+func (w *reportWatch) OnRTPPacket(mediaID int, packet *RTPPacket) {
+	w.reporter.onRTP(mediaID, packet.raw, time.Now())
+	forwardRTP(w.MediaHandler, mediaID, packet)
+}
+
+func (w *reportWatch) OnRTCP(mediaID int, packet []byte) {
+	w.reporter.onRTCP(mediaID, packet, time.Now())
+	w.MediaHandler.OnRTCP(mediaID, packet)
+}
+
+func (w *reportWatch) unwrap() MediaHandler {
+	return w.MediaHandler
+}
+
+// mediaClockRate returns RTP clock rate for the SDP item.
+// Returns 0 if not known
+func mediaClockRate(item *SdpItem) int {
+	switch m := item.Media.(type) {
+	case *MediaH264:
+		return m.ClockRate
+	case *MediaH265:
+		return m.ClockRate
+	case *MediaMPEG4:
+		return m.ClockRate
+	}
+
+	// static payload types
+	// https://datatracker.ietf.org/doc/html/rfc3551#section-6
+	switch item.Format {
+	case 0, 3, 4, 5, 7, 8, 9, 12, 13, 15, 18:
+		return 8000
+	case 6:
+		return 16000
+	case 10, 11:
+		return 44100
+	case 14, 25, 26, 28, 31, 32, 33, 34:
+		return 90000
+	}
+
+	return 0
+}
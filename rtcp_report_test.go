@@ -0,0 +1,182 @@
+package rtsp
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testRTPHeader(seq uint16, timestamp, ssrc uint32) []byte {
+	b := make([]byte, rtpHeaderSize)
+	b[0] = RTPVersion << 6
+	b[1] = 96
+	binary.BigEndian.PutUint16(b[2:], seq)
+	binary.BigEndian.PutUint32(b[4:], timestamp)
+	binary.BigEndian.PutUint32(b[8:], ssrc)
+
+	return b
+}
+
+func TestRTCPReporter_Loss(t *testing.T) {
+	assert := assert.New(t)
+
+	r := newRTCPReporter()
+	now := r.start
+
+	// 65530..65535, 0..9 without 3
+	seq := uint16(65530)
+	for i := 0; i < 16; i++ {
+		now = now.Add(10 * time.Millisecond)
+		if seq != 3 {
+			r.onRTP(0, testRTPHeader(seq, uint32(i)*900, 1), now)
+		}
+		seq++
+	}
+
+	packets, err := ParseRTCP(r.report(0, now))
+	if !assert.NoError(err) || !assert.Len(packets, 2) {
+		return
+	}
+
+	rr, ok := packets[0].(*RTCPReceiverReport)
+	if assert.True(ok) && assert.Len(rr.Reports, 1) {
+		assert.Equal(r.ssrc, rr.SSRC)
+
+		report := rr.Reports[0]
+		assert.Equal(uint32(1), report.SSRC)
+		assert.Equal(uint32(1<<16+9), report.HighestSequence)
+		assert.Equal(int32(1), report.PacketsLost)
+		// 1 of 15 packets since the first one in probation
+		assert.Equal(uint8(256/15), report.FractionLost)
+		assert.Equal(uint32(0), report.Jitter)
+	}
+
+	assert.Equal(
+		&RTCPSourceDescription{
+			Chunks: []RTCPSDESChunk{
+				{
+					Source: r.ssrc,
+					Items:  []RTCPSDESItem{{Type: SDESCNAME, Text: r.cname}},
+				},
+			},
+		},
+		packets[1],
+	)
+
+	// no loss in the next interval
+	r.onRTP(0, testRTPHeader(10, 16*900, 1), now.Add(10*time.Millisecond))
+	packets, _ = ParseRTCP(r.report(0, now))
+	if rr, ok := packets[0].(*RTCPReceiverReport); assert.True(ok) {
+		assert.Equal(uint8(0), rr.Reports[0].FractionLost)
+		assert.Equal(int32(1), rr.Reports[0].PacketsLost)
+	}
+
+	// no packets for the media
+	assert.Nil(r.report(1, now))
+}
+
+func TestRTCPReporter_JitterAndLSR(t *testing.T) {
+	assert := assert.New(t)
+
+	r := newRTCPReporter()
+	r.setClockRate(0, 1000)
+	now := r.start
+
+	// every second packet is delayed for 4ms
+	for i := 0; i < 100; i++ {
+		arrival := now.Add(time.Duration(i) * 10 * time.Millisecond)
+		if i%2 == 1 {
+			arrival = arrival.Add(4 * time.Millisecond)
+		}
+		r.onRTP(0, testRTPHeader(uint16(i), uint32(i)*10, 1), arrival)
+	}
+
+	sr := []byte{
+		0x80, 0xC8, 0x00, 0x06,
+		0x00, 0x00, 0x00, 0x01,
+		0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+	}
+	r.onRTCP(0, sr, now)
+
+	packets, err := ParseRTCP(r.report(0, now.Add(1500*time.Millisecond)))
+	if !assert.NoError(err) {
+		return
+	}
+
+	if rr, ok := packets[0].(*RTCPReceiverReport); assert.True(ok) {
+		report := rr.Reports[0]
+		assert.Equal(uint32(3), report.Jitter)
+		assert.Equal(uint32(0x33445566), report.LastSR)
+		assert.Equal(uint32(1.5*65536), report.DelaySinceLastSR)
+	}
+}
+
+func TestRTCPReporter_delaySinceLastSR(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(uint32(0), delaySinceLastSR(-time.Second))
+	assert.Equal(uint32(0x8000), delaySinceLastSR(500*time.Millisecond))
+	assert.Equal(uint32(18*3600*65536), delaySinceLastSR(18*time.Hour))
+	assert.Equal(uint32(0xFFFFFFFF), delaySinceLastSR(50*time.Hour))
+	assert.Equal(uint32(0xFFFFFFFF), delaySinceLastSR(0x10000*time.Second))
+}
+
+type testInterleavedConn struct {
+	packets chan []byte
+}
+
+func (c *testInterleavedConn) WriteInterleaved(channel int, packet []byte) error {
+	if channel == 1 {
+		select {
+		case c.packets <- packet:
+		default:
+		}
+	}
+	return nil
+}
+
+func (c *testInterleavedConn) ReadMessage(reader *bufio.Reader) error {
+	return io.EOF
+}
+
+func TestTransportTCP_ReceiverReports(t *testing.T) {
+	assert := assert.New(t)
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+
+	conn := &testInterleavedConn{packets: make(chan []byte, 1)}
+	transport := NewTransportTCPWithConn(bufio.NewReader(pr), conn)
+	transport.ReceiverReports = true
+	transport.reporter.interval = 20 * time.Millisecond
+	defer transport.Close()
+
+	if _, err := transport.Setup(0); !assert.NoError(err) {
+		return
+	}
+
+	transport.Play(testHandler{})
+
+	w := bufio.NewWriter(pw)
+	for i := 0; i < 3; i++ {
+		writeInterleaved(w, 0, testRTPHeader(uint16(i), 0, 1))
+	}
+
+	select {
+	case packet := <-conn.packets:
+		packets, err := ParseRTCP(packet)
+		if assert.NoError(err) && assert.Len(packets, 2) {
+			assert.IsType(&RTCPReceiverReport{}, packets[0])
+			assert.IsType(&RTCPSourceDescription{}, packets[1])
+		}
+	case <-time.After(time.Second):
+		assert.Fail("timeout")
+	}
+}
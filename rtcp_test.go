@@ -0,0 +1,163 @@
+package rtsp
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRTCP_Compound(t *testing.T) {
+	assert := assert.New(t)
+
+	data := []byte{
+		// SR with one report block
+		0x81, 0xC8, 0x00, 0x0C,
+		0x00, 0x00, 0x00, 0x01, // ssrc
+		0xE9, 0x13, 0xA0, 0x80, 0x80, 0x00, 0x00, 0x00, // ntp
+		0x00, 0x01, 0x00, 0x00, // rtp time
+		0x00, 0x00, 0x00, 0x0A, // packets
+		0x00, 0x00, 0x04, 0x00, // octets
+		0x00, 0x00, 0x00, 0x02, // report ssrc
+		0x40, 0xFF, 0xFF, 0xFE, // fraction lost, lost=-2
+		0x00, 0x01, 0x00, 0x10, // highest sequence
+		0x00, 0x00, 0x00, 0x20, // jitter
+		0x00, 0x00, 0x00, 0x30, // lsr
+		0x00, 0x00, 0x00, 0x40, // dlsr
+		// SDES with CNAME
+		0x81, 0xCA, 0x00, 0x03,
+		0x00, 0x00, 0x00, 0x01,
+		0x01, 0x02, 'h', 'i',
+		0x00, 0x00, 0x00, 0x00,
+		// BYE with reason
+		0x81, 0xCB, 0x00, 0x02,
+		0x00, 0x00, 0x00, 0x01,
+		0x03, 'e', 'n', 'd',
+	}
+
+	packets, err := ParseRTCP(data)
+	if !assert.NoError(err) || !assert.Len(packets, 3) {
+		return
+	}
+
+	sr, ok := packets[0].(*RTCPSenderReport)
+	if assert.True(ok) {
+		assert.Equal(uint32(1), sr.SSRC)
+		assert.Equal(uint32(0x10000), sr.RTPTime)
+		assert.Equal(uint32(10), sr.PacketCount)
+		assert.Equal(uint32(1024), sr.OctetCount)
+		assert.Equal(
+			time.Date(2023, 12, 1, 0, 0, 0, int(500*time.Millisecond), time.UTC),
+			sr.Time().UTC(),
+		)
+		assert.Equal(
+			[]RTCPReceptionReport{
+				{
+					SSRC:             2,
+					FractionLost:     0x40,
+					PacketsLost:      -2,
+					HighestSequence:  0x10010,
+					Jitter:           0x20,
+					LastSR:           0x30,
+					DelaySinceLastSR: 0x40,
+				},
+			},
+			sr.Reports,
+		)
+	}
+
+	assert.Equal(
+		&RTCPSourceDescription{
+			Chunks: []RTCPSDESChunk{
+				{
+					Source: 1,
+					Items:  []RTCPSDESItem{{Type: SDESCNAME, Text: "hi"}},
+				},
+			},
+		},
+		packets[1],
+	)
+
+	assert.Equal(&RTCPGoodbye{Sources: []uint32{1}, Reason: "end"}, packets[2])
+
+	assert.True(hasRTCPBye(data))
+	assert.False(hasRTCPBye(data[:52]))
+}
+
+func TestParseRTCP_Feedback(t *testing.T) {
+	assert := assert.New(t)
+
+	data := []byte{
+		// RR without report blocks
+		0x80, 0xC9, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x01,
+		// PLI
+		0x81, 0xCE, 0x00, 0x02,
+		0x00, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x02,
+		// Generic NACK
+		0x81, 0xCD, 0x00, 0x03,
+		0x00, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x02,
+		0x00, 0x10, 0x00, 0x03,
+		// APP with padding
+		0xA5, 0xCC, 0x00, 0x03,
+		0x00, 0x00, 0x00, 0x01,
+		'T', 'E', 'S', 'T',
+		0x00, 0x00, 0x00, 0x04,
+	}
+
+	packets, err := ParseRTCP(data)
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal(
+		[]RTCPPacket{
+			&RTCPReceiverReport{SSRC: 1},
+			&RTCPFeedback{Type: RTCPTypePSFB, Format: 1, SenderSSRC: 1, MediaSSRC: 2, FCI: []byte{}},
+			&RTCPFeedback{Type: RTCPTypeRTPFB, Format: 1, SenderSSRC: 1, MediaSSRC: 2, FCI: []byte{0x00, 0x10, 0x00, 0x03}},
+			&RTCPApp{SubType: 5, SSRC: 1, Name: "TEST", Data: []byte{}},
+		},
+		packets,
+	)
+}
+
+func TestParseRTCP_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"short", []byte{0x80, 0xC9, 0x00}},
+		{"version", []byte{0x40, 0xC9, 0x00, 0x00}},
+		{"length", []byte{0x80, 0xC9, 0x00, 0x02, 0x00, 0x00, 0x00, 0x01}},
+		{"reports", []byte{0x81, 0xC9, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01}},
+		{"sdes", []byte{0x81, 0xCA, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01}},
+		{"padding", []byte{0xA0, 0xC9, 0x00, 0x01, 0x00, 0x00, 0x00, 0x08}},
+	}
+
+	for _, test := range tests {
+		_, err := ParseRTCP(test.data)
+		assert.ErrorIs(t, err, ErrInvalidRTCPPacket, test.name)
+	}
+}
+
+func TestTransportTCP_EndOnBye(t *testing.T) {
+	assert := assert.New(t)
+
+	var stream bytes.Buffer
+	w := bufio.NewWriter(&stream)
+	writeInterleaved(w, 1, []byte{0x80, 0xC9, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01})
+	writeInterleaved(w, 1, []byte{0x81, 0xCB, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01})
+
+	transport := NewTransportTCP(bufio.NewReader(&stream))
+	transport.EndOnBye = true
+	if _, err := transport.Setup(0); !assert.NoError(err) {
+		return
+	}
+
+	transport.Play(testHandler{})
+	assert.ErrorIs(<-transport.Err(), ErrEndOfStream)
+}
@@ -0,0 +1,408 @@
+package rtsp
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+var ErrInvalidRTPPacket = fmt.Errorf("invalid rtp packet")
+
+const (
+	// RTPVersion is the RTP version defined in RFC 3550
+	RTPVersion = 2
+
+	rtpHeaderSize = 12
+
+	// RTPExtensionOneByte is the profile of RFC 8285 one-byte header extensions
+	RTPExtensionOneByte = 0xBEDE
+	// RTPExtensionTwoByte is the profile of RFC 8285 two-byte header extensions.
+	// Lower 4 bits are application-dependent
+	RTPExtensionTwoByte = 0x1000
+)
+
+// RTPPacket is the RTP packet.
+// Unmarshal does not copy data, so Payload and extensions
+// are valid while the source buffer is not changed.
+// https://datatracker.ietf.org/doc/html/rfc3550#section-5.1
+type RTPPacket struct {
+	// Version is the RTP version. Marshal uses RTPVersion if 0
+	Version uint8
+	// Padding is set if packet has padding at the end of payload
+	Padding bool
+	// PaddingSize is the number of padding bytes including the last one
+	PaddingSize uint8
+	Marker      bool
+	PayloadType uint8
+
+	SequenceNumber uint16
+	Timestamp      uint32
+	SSRC           uint32
+	CSRC           []uint32
+
+	// Extension is set if packet has header extension
+	Extension bool
+	// ExtensionProfile is the profile-defined value of the header extension.
+	// RTPExtensionOneByte or RTPExtensionTwoByte for RFC 8285 extensions
+	ExtensionProfile uint16
+	// Extensions is the list of RFC 8285 header extension elements
+	Extensions []RTPExtension
+	// ExtensionPayload is the header extension data
+	// if profile is not defined with RFC 8285
+	ExtensionPayload []byte
+
+	Payload []byte
+
+	// raw is the source buffer of Unmarshal
+	raw []byte
+}
+
+// RTPExtension is the header extension element
+// https://datatracker.ietf.org/doc/html/rfc8285
+type RTPExtension struct {
+	ID      uint8
+	Payload []byte
+}
+
+// isTwoByteProfile checks if profile defines RFC 8285 two-byte header extensions
+func isTwoByteProfile(profile uint16) bool {
+	return profile&0xFFF0 == RTPExtensionTwoByte
+}
+
+// Unmarshal parses the RTP packet.
+// CSRC and Extensions reuse capacity of the previous values
+func (p *RTPPacket) Unmarshal(data []byte) error {
+	if len(data) < rtpHeaderSize {
+		return fmt.Errorf("%w: header too short", ErrInvalidRTPPacket)
+	}
+
+	p.Version = data[0] >> 6
+	if p.Version != RTPVersion {
+		return fmt.Errorf("%w: unsupported version %d", ErrInvalidRTPPacket, p.Version)
+	}
+
+	p.Padding = data[0]&0x20 != 0
+	p.Extension = data[0]&0x10 != 0
+	csrcCount := int(data[0] & 0x0F)
+	p.Marker = data[1]&0x80 != 0
+	p.PayloadType = data[1] & 0x7F
+	p.SequenceNumber = binary.BigEndian.Uint16(data[2:4])
+	p.Timestamp = binary.BigEndian.Uint32(data[4:8])
+	p.SSRC = binary.BigEndian.Uint32(data[8:12])
+
+	offset := rtpHeaderSize
+
+	if len(data) < offset+csrcCount*4 {
+		return fmt.Errorf("%w: csrc list too short", ErrInvalidRTPPacket)
+	}
+
+	p.CSRC = p.CSRC[:0]
+	for i := 0; i < csrcCount; i++ {
+		p.CSRC = append(p.CSRC, binary.BigEndian.Uint32(data[offset:]))
+		offset += 4
+	}
+
+	p.ExtensionProfile = 0
+	p.Extensions = p.Extensions[:0]
+	p.ExtensionPayload = nil
+
+	if p.Extension {
+		if len(data) < offset+4 {
+			return fmt.Errorf("%w: extension header too short", ErrInvalidRTPPacket)
+		}
+
+		p.ExtensionProfile = binary.BigEndian.Uint16(data[offset:])
+		size := int(binary.BigEndian.Uint16(data[offset+2:])) * 4
+		offset += 4
+
+		if len(data) < offset+size {
+			return fmt.Errorf("%w: extension too short", ErrInvalidRTPPacket)
+		}
+
+		p.ExtensionPayload = data[offset : offset+size]
+		offset += size
+
+		if err := p.unmarshalExtensions(); err != nil {
+			return err
+		}
+	}
+
+	end := len(data)
+	p.PaddingSize = 0
+
+	if p.Padding {
+		p.PaddingSize = data[end-1]
+		if p.PaddingSize == 0 || offset+int(p.PaddingSize) > end {
+			return fmt.Errorf("%w: invalid padding %d", ErrInvalidRTPPacket, p.PaddingSize)
+		}
+		end -= int(p.PaddingSize)
+	}
+
+	p.Payload = data[offset:end]
+	p.raw = data
+
+	return nil
+}
+
+// unmarshalExtensions parses RFC 8285 elements from ExtensionPayload
+func (p *RTPPacket) unmarshalExtensions() error {
+	data := p.ExtensionPayload
+
+	switch {
+	case p.ExtensionProfile == RTPExtensionOneByte:
+		for i := 0; i < len(data); {
+			if data[i] == 0 {
+				// padding
+				i++
+				continue
+			}
+
+			id := data[i] >> 4
+			if id == 15 {
+				// reserved, stop processing
+				break
+			}
+
+			size := int(data[i]&0x0F) + 1
+			i++
+
+			if i+size > len(data) {
+				return fmt.Errorf("%w: extension element too short", ErrInvalidRTPPacket)
+			}
+
+			p.Extensions = append(p.Extensions, RTPExtension{
+				ID:      id,
+				Payload: data[i : i+size],
+			})
+			i += size
+		}
+
+	case isTwoByteProfile(p.ExtensionProfile):
+		for i := 0; i < len(data); {
+			if data[i] == 0 {
+				// padding
+				i++
+				continue
+			}
+
+			if i+2 > len(data) {
+				return fmt.Errorf("%w: extension element too short", ErrInvalidRTPPacket)
+			}
+
+			id := data[i]
+			size := int(data[i+1])
+			i += 2
+
+			if i+size > len(data) {
+				return fmt.Errorf("%w: extension element too short", ErrInvalidRTPPacket)
+			}
+
+			p.Extensions = append(p.Extensions, RTPExtension{
+				ID:      id,
+				Payload: data[i : i+size],
+			})
+			i += size
+		}
+
+	default:
+		return nil
+	}
+
+	p.ExtensionPayload = nil
+
+	return nil
+}
+
+// GetExtension returns payload of the header extension element.
+// Returns nil if element is not defined
+func (p *RTPPacket) GetExtension(id uint8) []byte {
+	for _, e := range p.Extensions {
+		if e.ID == id {
+			return e.Payload
+		}
+	}
+
+	return nil
+}
+
+// extensionProfile returns profile for Marshal.
+// If profile is not defined selects one-byte or two-byte header
+// by elements ID and size
+func (p *RTPPacket) extensionProfile() uint16 {
+	if p.ExtensionProfile != 0 || len(p.Extensions) == 0 {
+		return p.ExtensionProfile
+	}
+
+	for _, e := range p.Extensions {
+		if e.ID == 0 || e.ID > 14 || len(e.Payload) == 0 || len(e.Payload) > 16 {
+			return RTPExtensionTwoByte
+		}
+	}
+
+	return RTPExtensionOneByte
+}
+
+// extensionSize returns size of the header extension data
+// aligned to 32 bits, without the extension header
+func (p *RTPPacket) extensionSize(profile uint16) int {
+	var size int
+
+	switch {
+	case len(p.Extensions) == 0:
+		size = len(p.ExtensionPayload)
+	case profile == RTPExtensionOneByte:
+		for _, e := range p.Extensions {
+			size += 1 + len(e.Payload)
+		}
+	default:
+		for _, e := range p.Extensions {
+			size += 2 + len(e.Payload)
+		}
+	}
+
+	return (size + 3) &^ 3
+}
+
+// MarshalSize returns size of the marshaled packet
+func (p *RTPPacket) MarshalSize() int {
+	size := rtpHeaderSize + len(p.CSRC)*4 + len(p.Payload)
+
+	if p.Extension || len(p.Extensions) != 0 {
+		size += 4 + p.extensionSize(p.extensionProfile())
+	}
+
+	if p.Padding {
+		size += int(p.PaddingSize)
+	}
+
+	return size
+}
+
+// Marshal returns the packet data
+func (p *RTPPacket) Marshal() ([]byte, error) {
+	buf := make([]byte, p.MarshalSize())
+
+	n, err := p.MarshalTo(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf[:n], nil
+}
+
+// MarshalTo writes the packet data into buf.
+// Returns number of bytes written
+func (p *RTPPacket) MarshalTo(buf []byte) (int, error) {
+	size := p.MarshalSize()
+	if len(buf) < size {
+		return 0, fmt.Errorf("buffer too short %d, required %d", len(buf), size)
+	}
+
+	if len(p.CSRC) > 15 {
+		return 0, fmt.Errorf("too many csrc %d", len(p.CSRC))
+	}
+
+	if p.Padding && p.PaddingSize == 0 {
+		return 0, fmt.Errorf("padding size is not defined")
+	}
+
+	version := p.Version
+	if version == 0 {
+		version = RTPVersion
+	}
+
+	buf[0] = version<<6 | uint8(len(p.CSRC))
+	if p.Padding {
+		buf[0] |= 0x20
+	}
+
+	buf[1] = p.PayloadType & 0x7F
+	if p.Marker {
+		buf[1] |= 0x80
+	}
+
+	binary.BigEndian.PutUint16(buf[2:], p.SequenceNumber)
+	binary.BigEndian.PutUint32(buf[4:], p.Timestamp)
+	binary.BigEndian.PutUint32(buf[8:], p.SSRC)
+
+	offset := rtpHeaderSize
+
+	for _, csrc := range p.CSRC {
+		binary.BigEndian.PutUint32(buf[offset:], csrc)
+		offset += 4
+	}
+
+	if p.Extension || len(p.Extensions) != 0 {
+		buf[0] |= 0x10
+
+		n, err := p.marshalExtension(buf[offset:])
+		if err != nil {
+			return 0, err
+		}
+		offset += n
+	}
+
+	offset += copy(buf[offset:], p.Payload)
+
+	if p.Padding {
+		padding := buf[offset : offset+int(p.PaddingSize)]
+		for i := range padding {
+			padding[i] = 0
+		}
+		padding[len(padding)-1] = p.PaddingSize
+		offset += len(padding)
+	}
+
+	return offset, nil
+}
+
+// marshalExtension writes the header extension
+func (p *RTPPacket) marshalExtension(buf []byte) (int, error) {
+	profile := p.extensionProfile()
+	size := p.extensionSize(profile)
+
+	if size/4 > 0xFFFF {
+		return 0, fmt.Errorf("extension too large %d", size)
+	}
+
+	binary.BigEndian.PutUint16(buf[0:], profile)
+	binary.BigEndian.PutUint16(buf[2:], uint16(size/4))
+
+	data := buf[4 : 4+size]
+	offset := 0
+
+	switch {
+	case len(p.Extensions) == 0:
+		offset = copy(data, p.ExtensionPayload)
+
+	case profile == RTPExtensionOneByte:
+		for _, e := range p.Extensions {
+			if e.ID == 0 || e.ID > 14 || len(e.Payload) == 0 || len(e.Payload) > 16 {
+				return 0, fmt.Errorf("invalid one-byte extension %d size %d", e.ID, len(e.Payload))
+			}
+
+			data[offset] = e.ID<<4 | uint8(len(e.Payload)-1)
+			offset += 1 + copy(data[offset+1:], e.Payload)
+		}
+
+	case isTwoByteProfile(profile):
+		for _, e := range p.Extensions {
+			if e.ID == 0 || len(e.Payload) > 255 {
+				return 0, fmt.Errorf("invalid two-byte extension %d size %d", e.ID, len(e.Payload))
+			}
+
+			data[offset] = e.ID
+			data[offset+1] = uint8(len(e.Payload))
+			offset += 2 + copy(data[offset+2:], e.Payload)
+		}
+
+	default:
+		return 0, fmt.Errorf("extension elements with profile %04X", profile)
+	}
+
+	// padding to 32 bits
+	for ; offset < size; offset++ {
+		data[offset] = 0
+	}
+
+	return 4 + size, nil
+}
@@ -0,0 +1,165 @@
+package rtsp
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRTPPacket_Unmarshal(t *testing.T) {
+	assert := assert.New(t)
+
+	data := []byte{
+		0xB1, 0xE0, 0x12, 0x34, // V=2 P X CC=1, M PT=96, seq
+		0x00, 0x01, 0x02, 0x03, // timestamp
+		0xAA, 0xBB, 0xCC, 0xDD, // ssrc
+		0x11, 0x22, 0x33, 0x44, // csrc
+		0xBE, 0xDE, 0x00, 0x02, // one-byte extension, 2 words
+		0x10, 0x01, 0x00, 0x21, // id=1 len=1, padding, id=2 len=2
+		0x02, 0x03, 0x00, 0x00, // id=2 data, padding
+		0xF0, 0xF1, // payload
+		0x00, 0x00, 0x03, // padding
+	}
+
+	var p RTPPacket
+	if !assert.NoError(p.Unmarshal(data)) {
+		return
+	}
+
+	assert.Equal(uint8(2), p.Version)
+	assert.True(p.Padding)
+	assert.Equal(uint8(3), p.PaddingSize)
+	assert.True(p.Marker)
+	assert.Equal(uint8(96), p.PayloadType)
+	assert.Equal(uint16(0x1234), p.SequenceNumber)
+	assert.Equal(uint32(0x00010203), p.Timestamp)
+	assert.Equal(uint32(0xAABBCCDD), p.SSRC)
+	assert.Equal([]uint32{0x11223344}, p.CSRC)
+	assert.True(p.Extension)
+	assert.Equal(uint16(RTPExtensionOneByte), p.ExtensionProfile)
+	assert.Equal(
+		[]RTPExtension{
+			{ID: 1, Payload: []byte{0x01}},
+			{ID: 2, Payload: []byte{0x02, 0x03}},
+		},
+		p.Extensions,
+	)
+	assert.Equal([]byte{0x02, 0x03}, p.GetExtension(2))
+	assert.Nil(p.GetExtension(3))
+	assert.Equal([]byte{0xF0, 0xF1}, p.Payload)
+
+	// zero-copy
+	data[28] = 0xFF
+	assert.Equal([]byte{0xFF, 0xF1}, p.Payload)
+	data[28] = 0xF0
+
+	// elements are written without padding between them
+	expected := append([]byte(nil), data...)
+	copy(expected[20:28], []byte{0x10, 0x01, 0x21, 0x02, 0x03, 0x00, 0x00, 0x00})
+
+	result, err := p.Marshal()
+	if assert.NoError(err) {
+		assert.Equal(expected, result)
+	}
+}
+
+func TestRTPPacket_TwoByteExtension(t *testing.T) {
+	assert := assert.New(t)
+
+	p := RTPPacket{
+		PayloadType:    97,
+		SequenceNumber: 1,
+		Timestamp:      2,
+		SSRC:           3,
+		Extensions: []RTPExtension{
+			{ID: 1, Payload: []byte{}},
+			{ID: 20, Payload: []byte{0x01, 0x02, 0x03}},
+		},
+		Payload: []byte{0xF0},
+	}
+
+	data, err := p.Marshal()
+	if !assert.NoError(err) {
+		return
+	}
+
+	assert.Equal(
+		[]byte{
+			0x90, 0x61, 0x00, 0x01,
+			0x00, 0x00, 0x00, 0x02,
+			0x00, 0x00, 0x00, 0x03,
+			0x10, 0x00, 0x00, 0x02,
+			0x01, 0x00, 0x14, 0x03,
+			0x01, 0x02, 0x03, 0x00,
+			0xF0,
+		},
+		data,
+	)
+
+	var result RTPPacket
+	if assert.NoError(result.Unmarshal(data)) {
+		assert.Equal(uint16(RTPExtensionTwoByte), result.ExtensionProfile)
+		assert.Equal(p.Extensions, result.Extensions)
+		assert.Equal(p.Payload, result.Payload)
+	}
+}
+
+func TestRTPPacket_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"short", []byte{0x80, 0x60, 0x00, 0x01}},
+		{"version", []byte{0x40, 0x60, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"csrc", []byte{0x81, 0x60, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0}},
+		{"extension", []byte{0x90, 0x60, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0xBE, 0xDE, 0, 1}},
+		{"padding", []byte{0xA0, 0x60, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0x00, 0x03}},
+	}
+
+	for _, test := range tests {
+		var p RTPPacket
+		assert.ErrorIs(t, p.Unmarshal(test.data), ErrInvalidRTPPacket, test.name)
+	}
+}
+
+type testPacketHandler struct {
+	testHandler
+	packets []RTPPacket
+}
+
+func (h *testPacketHandler) OnRTPPacket(mediaID int, packet *RTPPacket) {
+	h.packets = append(h.packets, RTPPacket{
+		SequenceNumber: packet.SequenceNumber,
+		Payload:        append([]byte(nil), packet.Payload...),
+	})
+}
+
+func TestTransportTCP_RTPPacketHandler(t *testing.T) {
+	assert := assert.New(t)
+
+	var stream bytes.Buffer
+	w := bufio.NewWriter(&stream)
+	writeInterleaved(w, 0, []byte{0x80, 0x60, 0x00, 0x01, 0, 0, 0, 0, 0, 0, 0, 0, 0xF0})
+	writeInterleaved(w, 0, []byte{0x80, 0x60, 0x00, 0x02})
+	writeInterleaved(w, 1, []byte{0x80, 0xC9, 0x00, 0x01, 0, 0, 0, 0})
+
+	transport := NewTransportTCP(bufio.NewReader(&stream))
+	if _, err := transport.Setup(0); !assert.NoError(err) {
+		return
+	}
+
+	handler := &testPacketHandler{}
+	transport.Play(handler)
+	<-transport.Err()
+
+	assert.Equal(
+		[]RTPPacket{{SequenceNumber: 1, Payload: []byte{0xF0}}},
+		handler.packets,
+	)
+	assert.Equal(
+		TransportStats{RTPPackets: 1, RTCPPackets: 1, Dropped: 1},
+		transport.Stats(),
+	)
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"net"
 	"net/url"
 	"strconv"
 	"strings"
@@ -12,6 +13,7 @@ import (
 // SDP: Session Description Protocol
 // https://datatracker.ietf.org/doc/html/rfc2327
 type SdpItem struct {
+	Type      string
 	Media     Media
 	Port      int
 	Transport string
@@ -44,6 +46,7 @@ func parse_m(line string) *SdpItem {
 	}
 
 	return &SdpItem{
+		Type:      fields[0],
 		Port:      port,
 		Transport: fields[2],
 		Format:    format,
@@ -89,7 +92,7 @@ func parse_a_control(control *url.URL, line string) (*url.URL, error) {
 		return control, nil
 	}
 
-	if strings.HasPrefix(line, "rtsp://") {
+	if strings.HasPrefix(line, "rtsp://") || strings.HasPrefix(line, "rtsps://") {
 		return url.Parse(line)
 	}
 
@@ -164,3 +167,49 @@ func ParseSDP(control *url.URL, data []byte) ([]*SdpItem, error) {
 
 	return result, nil
 }
+
+// FormatSDP makes SDP from the list of SDP Items.
+// Each media gets relative control URL trackID=N, where N is the media index.
+// Media attributes are defined if media implements SdpFormatter.
+// Address is the origin address, could be nil
+func FormatSDP(address net.IP, items []*SdpItem) ([]byte, error) {
+	var b bytes.Buffer
+
+	addrType, anyAddress := "IP4", "0.0.0.0"
+	if address == nil {
+		address = net.IPv4(127, 0, 0, 1)
+	} else if address.To4() == nil {
+		addrType, anyAddress = "IP6", "::"
+	}
+
+	fmt.Fprint(&b, "v=0\r\n")
+	fmt.Fprintf(&b, "o=- 0 0 IN %s %s\r\n", addrType, address)
+	fmt.Fprint(&b, "s=Stream\r\n")
+	fmt.Fprintf(&b, "c=IN %s %s\r\n", addrType, anyAddress)
+	fmt.Fprint(&b, "t=0 0\r\n")
+
+	for mediaID, item := range items {
+		if item.Type == "" || strings.ContainsAny(item.Type, " \t\r\n") {
+			return nil, fmt.Errorf("invalid type %q for media %d", item.Type, mediaID)
+		}
+
+		transport := item.Transport
+		if transport == "" {
+			transport = "RTP/AVP"
+		}
+
+		fmt.Fprintf(&b, "m=%s %d %s %d\r\n", item.Type, item.Port, transport, item.Format)
+
+		if f, ok := item.Media.(SdpFormatter); ok {
+			fmt.Fprintf(&b, "a=rtpmap:%d %s\r\n", item.Format, f.FormatRTPMap())
+
+			if fmtp := f.FormatFMTP(); fmtp != "" {
+				fmt.Fprintf(&b, "a=fmtp:%d %s\r\n", item.Format, fmtp)
+			}
+		}
+
+		fmt.Fprintf(&b, "a=control:trackID=%d\r\n", mediaID)
+	}
+
+	return b.Bytes(), nil
+}
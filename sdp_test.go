@@ -1,6 +1,7 @@
 package rtsp
 
 import (
+	"net"
 	"net/url"
 	"strings"
 	"testing"
@@ -34,6 +35,7 @@ func TestSdp_ParseSDP(t *testing.T) {
 
 	expectedSdp := []*SdpItem{
 		{
+			Type:      "audio",
 			Port:      5004,
 			Transport: "RTP/AVP",
 			Format:    96,
@@ -47,6 +49,7 @@ func TestSdp_ParseSDP(t *testing.T) {
 			},
 		},
 		{
+			Type:      "video",
 			Port:      5006,
 			Transport: "RTP/AVP",
 			Format:    97,
@@ -89,6 +92,7 @@ func TestSdp_parse_a_control(t *testing.T) {
 
 		expectedSdp := []*SdpItem{
 			{
+				Type:      "video",
 				Port:      8002,
 				Transport: "RTP/AVP",
 				Format:    31,
@@ -117,6 +121,7 @@ func TestSdp_parse_a_control(t *testing.T) {
 
 		expectedSdp := []*SdpItem{
 			{
+				Type:      "video",
 				Port:      8002,
 				Transport: "RTP/AVP",
 				Format:    31,
@@ -146,6 +151,7 @@ func TestSdp_parse_a_control(t *testing.T) {
 
 		expectedSdp := []*SdpItem{
 			{
+				Type:      "video",
 				Port:      8002,
 				Transport: "RTP/AVP",
 				Format:    31,
@@ -175,6 +181,7 @@ func TestSdp_parse_a_control(t *testing.T) {
 
 		expectedSdp := []*SdpItem{
 			{
+				Type:      "video",
 				Port:      8002,
 				Transport: "RTP/AVP",
 				Format:    31,
@@ -209,3 +216,72 @@ func TestSdp_parse_a_rtpmap(t *testing.T) {
 		require.True(ok)
 	})
 }
+
+func TestSdp_FormatSDP(t *testing.T) {
+	require := require.New(t)
+
+	items := []*SdpItem{
+		{
+			Type:      "video",
+			Transport: "RTP/AVP",
+			Format:    96,
+			Media: &MediaH264{
+				ClockRate:         90000,
+				PacketizationMode: 1,
+				ProfileLevelID:    []byte{0x42, 0x80, 0x14},
+				SPS:               []byte{0x67, 0x42, 0x80, 0x14},
+				PPS:               []byte{0x68, 0xce, 0x06, 0xe2},
+			},
+		},
+		{
+			Type:      "audio",
+			Transport: "RTP/AVP",
+			Format:    97,
+			Media: &MediaMPEG4{
+				ClockRate:      8000,
+				Mode:           "AAC-hbr",
+				ProfileLevelID: 15,
+				Config:         []byte{0x15, 0x88},
+			},
+		},
+	}
+
+	data, err := FormatSDP(nil, items)
+	require.NoError(err)
+
+	require.Contains(string(data), "c=IN IP4 0.0.0.0\r\n")
+	require.Contains(string(data), "a=fmtp:96 packetization-mode=1;profile-level-id=428014;sprop-parameter-sets=Z0KAFA==,aM4G4g==\r\n")
+	require.Contains(string(data), "a=fmtp:97 streamtype=5;profile-level-id=15;mode=AAC-hbr;sizelength=13;indexlength=3;indexdeltalength=3;config=1588\r\n")
+
+	u, _ := url.Parse("rtsp://test.local/live")
+	s, err := ParseSDP(u, data)
+	require.NoError(err)
+
+	c1, _ := url.Parse("rtsp://test.local/live/trackID=0")
+	c2, _ := url.Parse("rtsp://test.local/live/trackID=1")
+	items[0].URL = c1
+	items[1].URL = c2
+
+	require.Equal(items, s)
+}
+
+func TestSdp_FormatSDP_Address(t *testing.T) {
+	require := require.New(t)
+
+	items := []*SdpItem{
+		{Type: "video", Format: 96},
+	}
+
+	data, err := FormatSDP(net.ParseIP("2001:db8::1"), items)
+	require.NoError(err)
+	require.Contains(string(data), "o=- 0 0 IN IP6 2001:db8::1\r\n")
+	require.Contains(string(data), "c=IN IP6 ::\r\n")
+	require.Contains(string(data), "m=video 0 RTP/AVP 96\r\n")
+	require.NotContains(string(data), "a=rtpmap")
+
+	for _, mediaType := range []string{"", "video audio"} {
+		items[0].Type = mediaType
+		_, err = FormatSDP(nil, items)
+		require.Error(err, mediaType)
+	}
+}
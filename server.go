@@ -0,0 +1,829 @@
+package rtsp
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrServerClosed = fmt.Errorf("server closed")
+
+// ServerHandler handles requests from RTSP clients.
+// Methods are called from the connection goroutine.
+// Each method returns response for the client, nil means 200 OK.
+// Session is nil for requests without Session header.
+type ServerHandler interface {
+	// OnOptions is called on OPTIONS request.
+	// Server defines Public header if response does not contain it.
+	OnOptions(session *ServerSession, request *Request) *Response
+	// OnDescribe is called on DESCRIBE request.
+	// Response body should contain SDP, for example from FormatSDP.
+	OnDescribe(session *ServerSession, request *Request) *Response
+	// OnAnnounce is called on ANNOUNCE request with SDP in the request body.
+	OnAnnounce(session *ServerSession, request *Request) *Response
+	// OnSetup is called on SETUP request before transport negotiation.
+	// Returns media index for the requested control URL.
+	// Session is created by the server if request has no Session header.
+	OnSetup(session *ServerSession, request *Request) (int, *Response)
+	// OnPlay is called on PLAY request.
+	// Media delivery with session WriteRTP starts after response is sent.
+	OnPlay(session *ServerSession, request *Request) *Response
+	// OnPause is called on PAUSE request.
+	OnPause(session *ServerSession, request *Request) *Response
+	// OnRecord is called on RECORD request.
+	// Media receiving to the session MediaHandler starts after response is sent.
+	OnRecord(session *ServerSession, request *Request) *Response
+	// OnTeardown is called on TEARDOWN request.
+	// Also called with nil request when session is expired
+	// or interleaved connection is closed.
+	OnTeardown(session *ServerSession, request *Request) *Response
+}
+
+// Server accepts RTSP connections and delivers media streams
+// over TCP-interleaved or UDP transport.
+type Server struct {
+	Handler ServerHandler
+	// ServerName is the value for the Server header. Not sent if empty
+	ServerName string
+	// SessionTimeout is the session lifetime without any request.
+	// Default is 60 seconds
+	SessionTimeout time.Duration
+	// UDPConfig is the socket options for UDP transport
+	UDPConfig UDPConfig
+	// TCPConfig is the socket options for accepted connections
+	// with the interleaved transport. LocalIP and Interface are not used
+	TCPConfig TCPConfig
+
+	lock      sync.Mutex
+	closed    bool
+	listeners map[net.Listener]struct{}
+	conns     map[*serverConn]struct{}
+	sessions  map[string]*ServerSession
+}
+
+// ListenAndServe listens on the TCP address and serves RTSP connections
+func (s *Server) ListenAndServe(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(listener)
+}
+
+// Serve accepts connections on the listener.
+// Always returns non-nil error. After Close returns ErrServerClosed
+func (s *Server) Serve(listener net.Listener) error {
+	s.lock.Lock()
+	if s.closed {
+		s.lock.Unlock()
+		listener.Close()
+		return ErrServerClosed
+	}
+	if s.listeners == nil {
+		s.listeners = make(map[net.Listener]struct{})
+	}
+	s.listeners[listener] = struct{}{}
+	s.lock.Unlock()
+
+	defer func() {
+		s.lock.Lock()
+		delete(s.listeners, listener)
+		s.lock.Unlock()
+
+		listener.Close()
+	}()
+
+	if s.SessionTimeout == 0 {
+		s.SessionTimeout = defaultSessionTimeout
+	}
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.lock.Lock()
+			closed := s.closed
+			s.lock.Unlock()
+
+			if closed {
+				return ErrServerClosed
+			}
+
+			return err
+		}
+
+		if err := s.TCPConfig.apply(conn); err != nil {
+			conn.Close()
+			continue
+		}
+
+		sc := &serverConn{
+			server:   s,
+			conn:     conn,
+			br:       bufio.NewReader(conn),
+			bw:       bufio.NewWriter(conn),
+			channels: make(map[int]*serverChannel),
+			sessions: make(map[*ServerSession]struct{}),
+		}
+
+		s.lock.Lock()
+		if s.conns == nil {
+			s.conns = make(map[*serverConn]struct{})
+		}
+		s.conns[sc] = struct{}{}
+		s.lock.Unlock()
+
+		go sc.serve()
+	}
+}
+
+// Close closes all listeners, connections and sessions
+func (s *Server) Close() error {
+	s.lock.Lock()
+	s.closed = true
+
+	for listener := range s.listeners {
+		listener.Close()
+	}
+
+	for sc := range s.conns {
+		sc.conn.Close()
+	}
+
+	sessions := s.sessions
+	s.sessions = nil
+	s.lock.Unlock()
+
+	for _, session := range sessions {
+		session.close()
+	}
+
+	return nil
+}
+
+func (s *Server) newSession(sc *serverConn) (*ServerSession, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	session := &ServerSession{
+		ID:     strings.ToUpper(hex.EncodeToString(id)),
+		server: s,
+		conn:   sc,
+		medias: make(map[int]*serverMedia),
+	}
+
+	if addr, ok := sc.conn.RemoteAddr().(*net.TCPAddr); ok {
+		session.remoteIP = addr.IP
+	}
+
+	session.timer = time.AfterFunc(s.SessionTimeout, func() {
+		s.expireSession(session)
+	})
+
+	s.lock.Lock()
+	if s.sessions == nil {
+		s.sessions = make(map[string]*ServerSession)
+	}
+	s.sessions[session.ID] = session
+	s.lock.Unlock()
+
+	return session, nil
+}
+
+func (s *Server) getSession(id string) *ServerSession {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.sessions[id]
+}
+
+// removeSession closes session and returns true if session was active
+func (s *Server) removeSession(session *ServerSession) bool {
+	s.lock.Lock()
+	_, ok := s.sessions[session.ID]
+	delete(s.sessions, session.ID)
+	s.lock.Unlock()
+
+	session.close()
+
+	return ok
+}
+
+func (s *Server) expireSession(session *ServerSession) {
+	if s.removeSession(session) {
+		s.Handler.OnTeardown(session, nil)
+	}
+}
+
+type serverChannel struct {
+	session *ServerSession
+	mediaID int
+	rtcp    bool
+}
+
+type serverConn struct {
+	server *Server
+	conn   net.Conn
+	br     *bufio.Reader
+	bw     *bufio.Writer
+	wlock  sync.Mutex
+
+	// lock protects channels and sessions.
+	// Channels are released when session is closed
+	lock sync.Mutex
+	// channels is the interleaved channels map
+	channels map[int]*serverChannel
+	// sessions with interleaved transport
+	sessions map[*ServerSession]struct{}
+}
+
+// maxInterleavedChannel is the maximum channel in the interleaved binary frame
+const maxInterleavedChannel = 255
+
+// channel returns the interleaved channel
+func (sc *serverConn) channel(channel int) *serverChannel {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+
+	return sc.channels[channel]
+}
+
+// bind reserves interleaved channels for the session media.
+// Returns false if channels are invalid or already in use
+func (sc *serverConn) bind(session *ServerSession, mediaID int, rtpChannel, rtcpChannel int) bool {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+
+	for _, channel := range []int{rtpChannel, rtcpChannel} {
+		if channel < 0 || channel > maxInterleavedChannel {
+			return false
+		}
+
+		if _, ok := sc.channels[channel]; ok {
+			return false
+		}
+	}
+
+	sc.channels[rtpChannel] = &serverChannel{session: session, mediaID: mediaID}
+	sc.channels[rtcpChannel] = &serverChannel{session: session, mediaID: mediaID, rtcp: true}
+	sc.sessions[session] = struct{}{}
+
+	return true
+}
+
+// release frees interleaved channels of the session
+func (sc *serverConn) release(session *ServerSession) {
+	sc.lock.Lock()
+	defer sc.lock.Unlock()
+
+	for channel, ch := range sc.channels {
+		if ch.session == session {
+			delete(sc.channels, channel)
+		}
+	}
+
+	delete(sc.sessions, session)
+}
+
+// WriteInterleaved sends RTP or RTCP packet in the interleaved binary frame
+func (sc *serverConn) WriteInterleaved(channel int, packet []byte) error {
+	sc.wlock.Lock()
+	defer sc.wlock.Unlock()
+
+	return writeInterleaved(sc.bw, channel, packet)
+}
+
+func (sc *serverConn) serve() {
+	s := sc.server
+
+	defer func() {
+		sc.conn.Close()
+
+		s.lock.Lock()
+		delete(s.conns, sc)
+		s.lock.Unlock()
+
+		// interleaved transport could not work without connection
+		sc.lock.Lock()
+		sessions := make([]*ServerSession, 0, len(sc.sessions))
+		for session := range sc.sessions {
+			sessions = append(sessions, session)
+		}
+		sc.lock.Unlock()
+
+		for _, session := range sessions {
+			s.expireSession(session)
+		}
+	}()
+
+	buf := make([]byte, interleavedPacketSize)
+	var rtp rtpReceiver
+
+	for {
+		b, err := sc.br.Peek(1)
+		if err != nil {
+			return
+		}
+
+		if b[0] == '$' {
+			channel, packet, err := readInterleaved(sc.br, buf)
+			if err != nil {
+				return
+			}
+
+			if ch := sc.channel(channel); ch != nil {
+				if ch.rtcp {
+					ch.session.receive(ch.mediaID, true, packet)
+				} else {
+					ch.session.receiveRTP(&rtp, ch.mediaID, packet)
+				}
+			}
+
+			continue
+		}
+
+		request, err := ReadRequest(sc.br)
+		if err != nil {
+			return
+		}
+
+		if err = request.ReadBody(sc.br); err != nil {
+			return
+		}
+
+		response, session := sc.handle(request)
+
+		if cseq := request.Header.Get("CSeq"); cseq != "" {
+			response.Header.Set("CSeq", cseq)
+		}
+
+		if s.ServerName != "" {
+			response.Header.Set("Server", s.ServerName)
+		}
+
+		sc.wlock.Lock()
+		err = WriteResponse(sc.bw, response)
+		sc.wlock.Unlock()
+
+		if err != nil {
+			return
+		}
+
+		if session != nil && response.StatusCode == StatusOK {
+			switch request.Method {
+			case MethodPlay, MethodRecord:
+				session.start()
+			case MethodPause:
+				session.stop()
+			}
+		}
+	}
+}
+
+// handle dispatches request to the handler.
+// Returns response and session from the request
+func (sc *serverConn) handle(request *Request) (*Response, *ServerSession) {
+	s := sc.server
+	h := s.Handler
+
+	var session *ServerSession
+
+	if id := request.Header.Get("Session"); id != "" {
+		id, _, _ = strings.Cut(id, ";")
+		session = s.getSession(strings.TrimSpace(id))
+		if session == nil {
+			return NewResponse(StatusSessionNotFound), nil
+		}
+
+		session.refresh()
+	}
+
+	var response *Response
+
+	switch request.Method {
+	case MethodOptions:
+		response = h.OnOptions(session, request)
+		response = defaultResponse(response)
+		if response.StatusCode == StatusOK && response.Header.Get("Public") == "" {
+			response.Header.Set("Public", strings.Join([]string{
+				MethodOptions,
+				MethodDescribe,
+				MethodAnnounce,
+				MethodSetup,
+				MethodPlay,
+				MethodPause,
+				MethodRecord,
+				MethodTeardown,
+				MethodGetParameter,
+				MethodSetParameter,
+			}, ", "))
+		}
+
+	case MethodDescribe:
+		response = h.OnDescribe(session, request)
+		response = defaultResponse(response)
+		if response.StatusCode == StatusOK && len(response.Body) != 0 {
+			if response.Header.Get("Content-Type") == "" {
+				response.Header.Set("Content-Type", SdpMimeType)
+			}
+
+			if response.Header.Get("Content-Base") == "" {
+				base := request.URL.String()
+				if !strings.HasSuffix(base, "/") {
+					base += "/"
+				}
+				response.Header.Set("Content-Base", base)
+			}
+		}
+
+	case MethodAnnounce:
+		response = defaultResponse(h.OnAnnounce(session, request))
+
+	case MethodSetup:
+		return sc.handleSetup(session, request)
+
+	case MethodPlay, MethodPause, MethodRecord, MethodTeardown:
+		if session == nil {
+			return NewResponse(StatusSessionNotFound), nil
+		}
+
+		switch request.Method {
+		case MethodPlay:
+			response = h.OnPlay(session, request)
+		case MethodPause:
+			response = h.OnPause(session, request)
+		case MethodRecord:
+			response = h.OnRecord(session, request)
+		case MethodTeardown:
+			response = h.OnTeardown(session, request)
+			s.removeSession(session)
+		}
+		response = defaultResponse(response)
+
+	case MethodGetParameter, MethodSetParameter:
+		// keep-alive
+		response = NewResponse(StatusOK)
+
+	default:
+		response = NewResponse(StatusNotImplemented)
+	}
+
+	if session != nil {
+		response.Header.Set("Session", session.ID)
+	}
+
+	return response, session
+}
+
+func (sc *serverConn) handleSetup(session *ServerSession, request *Request) (*Response, *ServerSession) {
+	s := sc.server
+
+	created := false
+	if session == nil {
+		var err error
+		if session, err = s.newSession(sc); err != nil {
+			return NewResponse(StatusInternalServerError), nil
+		}
+		created = true
+	}
+
+	mediaID, response := s.Handler.OnSetup(session, request)
+	response = defaultResponse(response)
+
+	if response.StatusCode == StatusOK {
+		transport, code := session.setup(sc, mediaID, request.Header.Get("Transport"))
+		if code == StatusOK {
+			response.Header.Set("Transport", transport)
+		} else {
+			response = NewResponse(code)
+		}
+	}
+
+	if response.StatusCode != StatusOK {
+		if created {
+			s.removeSession(session)
+		}
+		return response, nil
+	}
+
+	response.Header.Set(
+		"Session",
+		fmt.Sprintf("%s;timeout=%d", session.ID, int(s.SessionTimeout.Seconds())),
+	)
+
+	return response, session
+}
+
+func defaultResponse(response *Response) *Response {
+	if response == nil {
+		return NewResponse(StatusOK)
+	}
+
+	if response.Header == nil {
+		response.Header = http.Header{}
+	}
+
+	return response
+}
+
+type serverMedia struct {
+	// conn is the connection for interleaved transport
+	conn *serverConn
+	// channel is the interleaved channel for RTP. -1 for UDP
+	channel int
+}
+
+// ServerSession is the RTSP session on the server side
+type ServerSession struct {
+	// ID is the session identifier
+	ID string
+
+	server   *Server
+	conn     *serverConn
+	remoteIP net.IP
+	timer    *time.Timer
+
+	lock    sync.Mutex
+	medias  map[int]*serverMedia
+	udp     *TransportUDP
+	handler MediaHandler
+	active  bool
+	started bool
+	closed  bool
+}
+
+// RemoteAddr returns address of the client connection
+func (ss *ServerSession) RemoteAddr() net.Addr {
+	return ss.conn.conn.RemoteAddr()
+}
+
+// SetMediaHandler defines handler for packets from the client.
+// Should be defined before RECORD response to receive RTP from the publisher
+func (ss *ServerSession) SetMediaHandler(handler MediaHandler) {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+
+	ss.handler = handler
+}
+
+// WriteRTP sends RTP packet to the client.
+// Packets are dropped until PLAY response is sent or on pause
+func (ss *ServerSession) WriteRTP(mediaID int, packet []byte) error {
+	return ss.write(mediaID, false, packet)
+}
+
+// WriteRTCP sends RTCP packet to the client.
+// Packets are dropped until PLAY response is sent or on pause
+func (ss *ServerSession) WriteRTCP(mediaID int, packet []byte) error {
+	return ss.write(mediaID, true, packet)
+}
+
+func (ss *ServerSession) write(mediaID int, rtcp bool, packet []byte) error {
+	ss.lock.Lock()
+	media := ss.medias[mediaID]
+	active := ss.active
+	closed := ss.closed
+	ss.lock.Unlock()
+
+	if closed {
+		return net.ErrClosed
+	}
+
+	if media == nil {
+		return fmt.Errorf("media %d is not defined", mediaID)
+	}
+
+	if !active {
+		return nil
+	}
+
+	if media.channel >= 0 {
+		channel := media.channel
+		if rtcp {
+			channel += 1
+		}
+		return media.conn.WriteInterleaved(channel, packet)
+	}
+
+	if rtcp {
+		return ss.udp.WriteRTCP(mediaID, packet)
+	}
+
+	return ss.udp.WriteRTP(mediaID, packet)
+}
+
+// setup negotiates transport for the media.
+// Returns transport header for response and status code
+func (ss *ServerSession) setup(sc *serverConn, mediaID int, header string) (string, int) {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+
+	if _, ok := ss.medias[mediaID]; ok {
+		return "", StatusMethodNotValidInThisState
+	}
+
+	// client could offer several transports separated by comma
+	transports, err := ParseTransportHeaders(header)
+	if err != nil {
+		return "", StatusBadRequest
+	}
+
+	for _, transport := range transports {
+		if transport.Multicast {
+			continue
+		}
+
+		switch transport.Profile {
+		case "RTP/AVP/TCP", "RTP/SAVP/TCP":
+			rtpChannel, rtcpChannel := mediaID*2, mediaID*2+1
+			if transport.HasInterleaved {
+				rtpChannel, rtcpChannel = transport.Interleaved[0], transport.Interleaved[1]
+			}
+
+			if !sc.bind(ss, mediaID, rtpChannel, rtcpChannel) {
+				continue
+			}
+
+			ss.medias[mediaID] = &serverMedia{conn: sc, channel: rtpChannel}
+
+			response := &TransportHeader{
+				Profile:        transport.Profile,
+				Interleaved:    [2]int{rtpChannel, rtcpChannel},
+				HasInterleaved: true,
+				Mode:           transport.Mode,
+			}
+
+			return response.String(), StatusOK
+
+		case "RTP/AVP", "RTP/AVP/UDP":
+			if transport.ClientPort[0] == 0 || ss.remoteIP == nil {
+				continue
+			}
+
+			if ss.udp == nil {
+				ss.udp = NewTransportUDPWithConfig(ss.remoteIP, ss.server.UDPConfig)
+			}
+
+			if _, err := ss.udp.Setup(mediaID); err != nil {
+				return "", StatusInternalServerError
+			}
+
+			c := ss.udp.getConn(mediaID)
+			c.setRemote(ss.remoteIP, transport.ClientPort[0], transport.ClientPort[1])
+			serverRTP, serverRTCP := c.localPorts()
+
+			ss.medias[mediaID] = &serverMedia{channel: -1}
+
+			response := &TransportHeader{
+				Profile:    "RTP/AVP",
+				ClientPort: transport.ClientPort,
+				ServerPort: [2]int{serverRTP, serverRTCP},
+				Mode:       transport.Mode,
+			}
+
+			return response.String(), StatusOK
+		}
+	}
+
+	return "", StatusUnsupportedTransport
+}
+
+// start activates media delivery after PLAY or RECORD response
+func (ss *ServerSession) start() {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+
+	ss.active = true
+
+	if ss.started || ss.closed {
+		return
+	}
+	ss.started = true
+
+	if ss.udp != nil {
+		ss.udp.Play(&sessionHandler{ss})
+
+		go func() {
+			if err := <-ss.udp.Err(); err != nil {
+				ss.server.expireSession(ss)
+			}
+		}()
+	}
+}
+
+// stop suspends media delivery after PAUSE response
+func (ss *ServerSession) stop() {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+
+	ss.active = false
+}
+
+// refresh resets session timeout
+func (ss *ServerSession) refresh() {
+	ss.timer.Reset(ss.server.SessionTimeout)
+}
+
+// receive delivers packet from the client to the media handler
+func (ss *ServerSession) receive(mediaID int, rtcp bool, packet []byte) {
+	ss.lock.Lock()
+	handler := ss.handler
+	closed := ss.closed
+	ss.lock.Unlock()
+
+	if closed || handler == nil {
+		return
+	}
+
+	if rtcp {
+		// receiver reports keep session alive
+		ss.refresh()
+		handler.OnRTCP(mediaID, packet)
+	} else {
+		handler.OnRTP(mediaID, packet)
+	}
+}
+
+// receiveRTP parses RTP packet if required and delivers it to the media handler
+func (ss *ServerSession) receiveRTP(rtp *rtpReceiver, mediaID int, packet []byte) {
+	ss.lock.Lock()
+	handler := ss.handler
+	closed := ss.closed
+	ss.lock.Unlock()
+
+	if closed || handler == nil {
+		return
+	}
+
+	if rtp.parse(handler, packet) {
+		rtp.deliver(handler, mediaID, packet)
+	}
+}
+
+// receivePacket delivers parsed RTP packet to the media handler
+func (ss *ServerSession) receivePacket(mediaID int, packet *RTPPacket) {
+	ss.lock.Lock()
+	handler := ss.handler
+	closed := ss.closed
+	ss.lock.Unlock()
+
+	if closed || handler == nil {
+		return
+	}
+
+	forwardRTP(handler, mediaID, packet)
+}
+
+func (ss *ServerSession) close() {
+	ss.lock.Lock()
+	defer ss.lock.Unlock()
+
+	if ss.closed {
+		return
+	}
+	ss.closed = true
+	ss.active = false
+
+	ss.timer.Stop()
+
+	if ss.udp != nil {
+		ss.udp.Close()
+	}
+
+	for _, media := range ss.medias {
+		if media.conn != nil {
+			media.conn.release(ss)
+		}
+	}
+}
+
+type sessionHandler struct {
+	session *ServerSession
+}
+
+func (h *sessionHandler) OnRTP(mediaID int, packet []byte) {
+	h.session.receive(mediaID, false, packet)
+}
+
+func (h *sessionHandler) OnRTPPacket(mediaID int, packet *RTPPacket) {
+	h.session.receivePacket(mediaID, packet)
+}
+
+func (h *sessionHandler) unwrap() MediaHandler {
+	h.session.lock.Lock()
+	defer h.session.lock.Unlock()
+
+	return h.session.handler
+}
+
+func (h *sessionHandler) OnRTCP(mediaID int, packet []byte) {
+	h.session.receive(mediaID, true, packet)
+}
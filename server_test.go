@@ -0,0 +1,347 @@
+package rtsp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testServerHandler struct {
+	items    []*SdpItem
+	packets  chan []byte
+	teardown chan *ServerSession
+}
+
+func (h *testServerHandler) OnOptions(session *ServerSession, request *Request) *Response {
+	return nil
+}
+
+func (h *testServerHandler) OnDescribe(session *ServerSession, request *Request) *Response {
+	if request.URL.Path != "/live" {
+		return NewResponse(StatusNotFound)
+	}
+
+	body, err := FormatSDP(nil, h.items)
+	if err != nil {
+		return NewResponse(StatusInternalServerError)
+	}
+
+	response := NewResponse(StatusOK)
+	response.Body = body
+	return response
+}
+
+func (h *testServerHandler) OnAnnounce(session *ServerSession, request *Request) *Response {
+	return nil
+}
+
+func (h *testServerHandler) OnSetup(session *ServerSession, request *Request) (int, *Response) {
+	return 0, nil
+}
+
+func (h *testServerHandler) OnPlay(session *ServerSession, request *Request) *Response {
+	go func() {
+		for {
+			if err := session.WriteRTP(0, []byte{0x80, 0x60, 0x00, 0x01}); err != nil {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}()
+
+	return nil
+}
+
+func (h *testServerHandler) OnPause(session *ServerSession, request *Request) *Response {
+	return nil
+}
+
+func (h *testServerHandler) OnRecord(session *ServerSession, request *Request) *Response {
+	session.SetMediaHandler(h)
+	return nil
+}
+
+func (h *testServerHandler) OnTeardown(session *ServerSession, request *Request) *Response {
+	h.teardown <- session
+	return nil
+}
+
+func (h *testServerHandler) OnRTP(mediaID int, packet []byte) {
+	select {
+	case h.packets <- append([]byte(nil), packet...):
+	default:
+	}
+}
+
+func (h *testServerHandler) OnRTCP(mediaID int, packet []byte) {}
+
+type testClientHandler struct {
+	packets chan []byte
+}
+
+func (h *testClientHandler) OnRTP(mediaID int, packet []byte) {
+	select {
+	case h.packets <- append([]byte(nil), packet...):
+	default:
+	}
+}
+
+func (h *testClientHandler) OnRTCP(mediaID int, packet []byte) {}
+
+func startTestServer(handler ServerHandler) (*Server, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, ""
+	}
+
+	s := &Server{
+		Handler:    handler,
+		ServerName: "test/0.0",
+	}
+	go s.Serve(listener)
+
+	return s, listener.Addr().String()
+}
+
+func TestServer_Play(t *testing.T) {
+	for _, useTCP := range []bool{false, true} {
+		name := "udp"
+		if useTCP {
+			name = "tcp"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			h := &testServerHandler{
+				items: []*SdpItem{
+					{
+						Type:   "video",
+						Format: 96,
+						Media:  NewMediaH264(90000),
+					},
+				},
+				teardown: make(chan *ServerSession, 1),
+			}
+
+			s, addr := startTestServer(h)
+			defer s.Close()
+
+			u, _ := url.Parse("rtsp://" + addr + "/live")
+			c := &Client{
+				URL:    u,
+				UseTCP: useTCP,
+			}
+			defer c.Close()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			if !assert.NoError(c.Start(ctx)) {
+				return
+			}
+
+			sdp := c.GetSDP()
+			if !assert.Len(sdp, 1) {
+				return
+			}
+			assert.Equal("rtsp://"+addr+"/live/trackID=0", sdp[0].URL.String())
+
+			if !assert.NoError(c.Setup(ctx, 0, sdp[0].URL)) {
+				return
+			}
+
+			handler := &testClientHandler{
+				packets: make(chan []byte, 1),
+			}
+
+			playErrorCh := make(chan error, 1)
+			go func() {
+				playErrorCh <- c.Play(ctx, handler)
+			}()
+
+			select {
+			case packet := <-handler.packets:
+				assert.Equal([]byte{0x80, 0x60, 0x00, 0x01}, packet)
+			case <-time.After(time.Second):
+				assert.Fail("timeout")
+			}
+
+			cancel()
+			assert.NoError(<-playErrorCh)
+
+			if useTCP {
+				// session is closed with connection
+				select {
+				case session := <-h.teardown:
+					assert.NotEmpty(session.ID)
+				case <-time.After(time.Second):
+					assert.Fail("teardown timeout")
+				}
+			}
+		})
+	}
+}
+
+func TestServer_Record(t *testing.T) {
+	assert := assert.New(t)
+
+	h := &testServerHandler{
+		packets:  make(chan []byte, 1),
+		teardown: make(chan *ServerSession, 1),
+	}
+
+	s, addr := startTestServer(h)
+	defer s.Close()
+
+	u, _ := url.Parse("rtsp://" + addr + "/publish")
+	p := NewPublisher(&Client{
+		URL:    u,
+		UseTCP: true,
+	})
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	items := []*SdpItem{
+		{
+			Type:   "video",
+			Format: 96,
+			Media:  NewMediaH264(90000),
+		},
+	}
+
+	if !assert.NoError(p.Start(ctx, items)) {
+		return
+	}
+
+	if !assert.NoError(p.Setup(ctx)) {
+		return
+	}
+
+	recordErrorCh := make(chan error, 1)
+	go func() {
+		recordErrorCh <- p.Record(ctx, nil)
+	}()
+
+	packet := []byte{0x80, 0x60, 0x00, 0x02}
+
+	var err error
+	for i := 0; i < 100; i++ {
+		if err = p.WriteRTP(0, packet); err != ErrNotRecording {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if !assert.NoError(err) {
+		return
+	}
+
+	select {
+	case received := <-h.packets:
+		assert.Equal(packet, received)
+	case <-time.After(time.Second):
+		assert.Fail("timeout")
+	}
+
+	cancel()
+	assert.NoError(<-recordErrorCh)
+}
+
+func TestServer_SessionNotFound(t *testing.T) {
+	assert := assert.New(t)
+
+	s, addr := startTestServer(&testServerHandler{})
+	defer s.Close()
+
+	u, _ := url.Parse("rtsp://" + addr + "/live")
+	c := &Client{URL: u}
+	defer c.Close()
+
+	ctx := context.Background()
+	if !assert.NoError(c.connect(ctx)) {
+		return
+	}
+
+	c.session = "UNKNOWN"
+	var statusError *StatusError
+	if assert.ErrorAs(c.Pause(ctx), &statusError) {
+		assert.Equal(StatusSessionNotFound, statusError.Code)
+		assert.Equal("Session Not Found", statusError.Reason)
+		assert.Equal(MethodPause, statusError.Method)
+	}
+}
+
+func TestServer_Interleaved(t *testing.T) {
+	assert := assert.New(t)
+
+	h := &testServerHandler{
+		teardown: make(chan *ServerSession, 4),
+	}
+
+	s, addr := startTestServer(h)
+	defer s.Close()
+
+	conn, err := net.Dial("tcp", addr)
+	if !assert.NoError(err) {
+		return
+	}
+	defer conn.Close()
+
+	br := bufio.NewReader(conn)
+	cseq := 0
+
+	send := func(method, header string) *Response {
+		cseq++
+		fmt.Fprintf(conn, "%s rtsp://%s/live/trackID=0 RTSP/1.0\r\nCSeq: %d\r\n%s\r\n", method, addr, cseq, header)
+
+		response, err := ReadResponse(br)
+		if err == nil {
+			err = response.ReadBody(br)
+		}
+		if !assert.NoError(err) {
+			return &Response{}
+		}
+
+		return response
+	}
+
+	response := send(MethodSetup, "Transport: RTP/AVP/TCP;interleaved=0-1\r\n")
+	if !assert.Equal(StatusOK, response.StatusCode) {
+		return
+	}
+	session, _, _ := strings.Cut(response.Header.Get("Session"), ";")
+
+	// RTCP channel is in use
+	response = send(MethodSetup, "Transport: RTP/AVP/TCP;interleaved=1-2\r\n")
+	assert.Equal(StatusUnsupportedTransport, response.StatusCode)
+
+	// channel out of range
+	response = send(MethodSetup, "Transport: RTP/AVP/TCP;interleaved=255-256\r\n")
+	assert.Equal(StatusUnsupportedTransport, response.StatusCode)
+
+	// channels are released on teardown
+	response = send(MethodTeardown, "Session: "+session+"\r\n")
+	assert.Equal(StatusOK, response.StatusCode)
+
+	response = send(MethodSetup, "Transport: RTP/AVP/TCP;interleaved=0-1\r\n")
+	assert.Equal(StatusOK, response.StatusCode)
+
+	s.lock.Lock()
+	assert.Len(s.sessions, 1)
+	for sc := range s.conns {
+		sc.lock.Lock()
+		assert.Len(sc.channels, 2)
+		assert.Len(sc.sessions, 1)
+		sc.lock.Unlock()
+	}
+	s.lock.Unlock()
+}
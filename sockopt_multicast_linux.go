@@ -0,0 +1,32 @@
+package rtsp
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// setMulticastOnly disables delivery of packets for groups joined
+// by other sockets on the same port. Linux delivers them by default
+// to the socket bound to the wildcard address.
+// Used with net.ListenConfig
+func setMulticastOnly(network, address string, c syscall.RawConn) error {
+	var err error
+
+	controlErr := c.Control(func(fd uintptr) {
+		if network == "udp4" {
+			err = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_MULTICAST_ALL, 0)
+		} else {
+			err = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_MULTICAST_ALL, 0)
+			if err == unix.ENOPROTOOPT {
+				// not supported before Linux 4.20
+				err = nil
+			}
+		}
+	})
+	if controlErr != nil {
+		return controlErr
+	}
+
+	return err
+}
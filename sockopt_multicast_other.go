@@ -0,0 +1,13 @@
+//go:build !linux
+
+package rtsp
+
+import (
+	"syscall"
+)
+
+// setMulticastOnly is not required on this platform,
+// socket receives only packets of the joined groups
+func setMulticastOnly(network, address string, c syscall.RawConn) error {
+	return nil
+}
@@ -0,0 +1,22 @@
+//go:build !aix && !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows
+
+package rtsp
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// setReuseAddr is not supported on this platform
+func setReuseAddr(network, address string, c syscall.RawConn) error {
+	return nil
+}
+
+// setSocketOptions is not supported on this platform
+func setSocketOptions(network string, c syscall.RawConn, readBuffer, tos int) error {
+	if readBuffer != 0 || tos != 0 {
+		return fmt.Errorf("socket options are not supported on this platform")
+	}
+
+	return nil
+}
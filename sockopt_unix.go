@@ -0,0 +1,72 @@
+//go:build aix || darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package rtsp
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// setReuseAddr allows several sockets to bind the same address and port.
+// Used with net.ListenConfig
+func setReuseAddr(network, address string, c syscall.RawConn) error {
+	var err error
+
+	controlErr := c.Control(func(fd uintptr) {
+		err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEADDR, 1)
+		if err == nil {
+			err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+		}
+	})
+	if controlErr != nil {
+		return controlErr
+	}
+
+	return err
+}
+
+// setSocketOptions sets receive buffer size and type of service.
+// IPv6 socket gets both traffic class and IPv4 type of service
+// for dual-stack connections. Used with net.Dialer
+func setSocketOptions(network string, c syscall.RawConn, readBuffer, tos int) error {
+	var err error
+
+	controlErr := c.Control(func(fd uintptr) {
+		if readBuffer != 0 {
+			err = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_RCVBUF, readBuffer)
+			if err != nil {
+				err = fmt.Errorf("set read buffer: %w", err)
+				return
+			}
+		}
+
+		if tos == 0 {
+			return
+		}
+
+		if strings.HasSuffix(network, "6") {
+			err = unix.SetsockoptInt(int(fd), unix.IPPROTO_IPV6, unix.IPV6_TCLASS, tos)
+			if err != nil {
+				err = fmt.Errorf("set dscp: %w", err)
+				return
+			}
+
+			// IPv4 type of service is not supported for IPv6 sockets on some platforms
+			_ = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_TOS, tos)
+			return
+		}
+
+		err = unix.SetsockoptInt(int(fd), unix.IPPROTO_IP, unix.IP_TOS, tos)
+		if err != nil {
+			err = fmt.Errorf("set dscp: %w", err)
+		}
+	})
+	if controlErr != nil {
+		return controlErr
+	}
+
+	return err
+}
@@ -0,0 +1,57 @@
+package rtsp
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+)
+
+// setReuseAddr allows several sockets to bind the same address and port.
+// Used with net.ListenConfig
+func setReuseAddr(network, address string, c syscall.RawConn) error {
+	var err error
+
+	controlErr := c.Control(func(fd uintptr) {
+		err = syscall.SetsockoptInt(syscall.Handle(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+	})
+	if controlErr != nil {
+		return controlErr
+	}
+
+	return err
+}
+
+// setSocketOptions sets receive buffer size and type of service.
+// Traffic class is not set for IPv6 sockets. Used with net.Dialer
+func setSocketOptions(network string, c syscall.RawConn, readBuffer, tos int) error {
+	var err error
+
+	controlErr := c.Control(func(fd uintptr) {
+		if readBuffer != 0 {
+			err = syscall.SetsockoptInt(syscall.Handle(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF, readBuffer)
+			if err != nil {
+				err = fmt.Errorf("set read buffer: %w", err)
+				return
+			}
+		}
+
+		if tos == 0 {
+			return
+		}
+
+		err = syscall.SetsockoptInt(syscall.Handle(fd), syscall.IPPROTO_IP, syscall.IP_TOS, tos)
+		if err != nil {
+			if strings.HasSuffix(network, "6") {
+				// IPv4 type of service is not supported for IPv6 sockets
+				err = nil
+			} else {
+				err = fmt.Errorf("set dscp: %w", err)
+			}
+		}
+	})
+	if controlErr != nil {
+		return controlErr
+	}
+
+	return err
+}
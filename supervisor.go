@@ -0,0 +1,336 @@
+package rtsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var ErrNoTracks = fmt.Errorf("no tracks to setup")
+
+// Default reconnection delays
+const (
+	defaultMinBackoff = time.Second
+	defaultMaxBackoff = 30 * time.Second
+)
+
+// DiscontinuityHandler is the optional MediaHandler interface
+// to be notified when the stream is interrupted.
+// Packets after this call belong to the new session,
+// RTP sequence numbers and timestamps are not continuous
+type DiscontinuityHandler interface {
+	OnDiscontinuity(err error)
+}
+
+// Supervisor keeps the stream playing.
+// Reconnects to the server with exponential backoff and jitter,
+// repeats DESCRIBE, SETUP and PLAY for the same tracks.
+// Tracks are matched by the control URL path, or by the media type
+// and order if control URLs are changed.
+// Media ID for the handler is the track index in the first SDP
+type Supervisor struct {
+	// Select reports whether the track should be received.
+	// Called for the first session only, next sessions receive tracks
+	// with the same control URL.
+	// Default selects tracks with supported media
+	Select func(item *SdpItem) bool
+	// MinBackoff is the delay before the first reconnection attempt.
+	// Default is 1 second
+	MinBackoff time.Duration
+	// MaxBackoff is the maximum delay between reconnection attempts.
+	// Default is 30 seconds
+	MaxBackoff time.Duration
+
+	client *Client
+
+	lock   sync.Mutex
+	sdp    []*SdpItem
+	tracks []supervisorTrack
+}
+
+// supervisorTrack is the selected track in the first session
+type supervisorTrack struct {
+	mediaID int
+	// path is the control URL path without host
+	path string
+	// kind is the media type and index is the order of the track
+	// among tracks with the same type
+	kind  string
+	index int
+}
+
+// NewSupervisor makes a new supervisor.
+// Client defines the server URL, transport and timeouts
+func NewSupervisor(client *Client) *Supervisor {
+	return &Supervisor{
+		client: client,
+	}
+}
+
+// GetSDP returns SDP items from the first session.
+// Returns nil if not connected yet
+func (s *Supervisor) GetSDP() []*SdpItem {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.sdp
+}
+
+// Run plays the stream until ctx.Done.
+// Reconnects on any error except of terminal errors like
+// 401 Unauthorized, 404 Not Found or ErrNoTracks.
+// Returns nil when ctx is canceled or the terminal error
+func (s *Supervisor) Run(ctx context.Context, handler MediaHandler) error {
+	attempt := 0
+
+	for {
+		received, err := s.session(ctx, handler)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if isTerminalError(err) {
+			return err
+		}
+
+		if received {
+			attempt = 0
+
+			if err == nil {
+				err = ErrClientClosed
+			}
+
+			if h, ok := handler.(DiscontinuityHandler); ok {
+				h.OnDiscontinuity(err)
+			}
+		}
+
+		timer := time.NewTimer(s.backoff(attempt))
+		attempt++
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// isTerminalError checks if the error could not be fixed by reconnection
+func isTerminalError(err error) bool {
+	if errors.Is(err, ErrNoTracks) {
+		return true
+	}
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+
+	switch statusErr.Code {
+	case StatusUnauthorized, StatusForbidden, StatusNotFound, StatusProxyAuthRequired:
+		return true
+	default:
+		return false
+	}
+}
+
+// backoff returns delay before the reconnection attempt.
+// Delay is random in the range from half to full exponential delay
+func (s *Supervisor) backoff(attempt int) time.Duration {
+	minBackoff := s.MinBackoff
+	if minBackoff <= 0 {
+		minBackoff = defaultMinBackoff
+	}
+
+	maxBackoff := s.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+
+	delay := minBackoff
+	for i := 0; i < attempt && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+
+	half := delay / 2
+
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+// session connects to the server and plays selected tracks.
+// Returns true if any packet is received
+func (s *Supervisor) session(ctx context.Context, handler MediaHandler) (bool, error) {
+	c := s.client
+	defer c.Close()
+
+	if err := c.Start(ctx); err != nil {
+		return false, err
+	}
+
+	items := c.GetSDP()
+
+	s.lock.Lock()
+	if s.sdp == nil {
+		s.sdp = items
+		s.tracks = selectTracks(items, s.Select)
+	}
+	tracks := s.tracks
+	s.lock.Unlock()
+
+	h := &supervisorHandler{
+		handler:  handler,
+		mediaIDs: matchTracks(tracks, items),
+	}
+
+	for mediaID := range items {
+		if _, ok := h.mediaIDs[mediaID]; !ok {
+			continue
+		}
+
+		if err := c.Setup(ctx, mediaID, items[mediaID].URL); err != nil {
+			return false, err
+		}
+	}
+
+	if len(h.mediaIDs) == 0 {
+		return false, ErrNoTracks
+	}
+
+	err := c.Play(ctx, h)
+
+	return h.received.Load(), err
+}
+
+// controlPath returns control URL path with query, without host
+func controlPath(u *url.URL) string {
+	path := u.EscapedPath()
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	return path
+}
+
+// selectTracks returns tracks to play in the first session.
+// Default selects tracks with supported media
+func selectTracks(items []*SdpItem, selectFn func(item *SdpItem) bool) []supervisorTrack {
+	var tracks []supervisorTrack
+
+	kinds := make(map[string]int)
+
+	for mediaID, item := range items {
+		index := kinds[item.Type]
+		kinds[item.Type]++
+
+		selected := item.Media != nil
+		if selectFn != nil {
+			selected = selectFn(item)
+		}
+
+		if selected && item.URL != nil {
+			tracks = append(tracks, supervisorTrack{
+				mediaID: mediaID,
+				path:    controlPath(item.URL),
+				kind:    item.Type,
+				index:   index,
+			})
+		}
+	}
+
+	return tracks
+}
+
+// matchTracks returns map of the media ID in the current session
+// to the media ID in the first session.
+// Tracks are matched by the control URL path. If path is not found,
+// track is matched by the media type and order
+func matchTracks(tracks []supervisorTrack, items []*SdpItem) map[int]int {
+	result := make(map[int]int)
+	used := make(map[int]bool)
+
+	match := func(track supervisorTrack, fn func(item *SdpItem, index int) bool) bool {
+		kinds := make(map[string]int)
+
+		for mediaID, item := range items {
+			index := kinds[item.Type]
+			kinds[item.Type]++
+
+			if used[mediaID] || item.URL == nil || !fn(item, index) {
+				continue
+			}
+
+			used[mediaID] = true
+			result[mediaID] = track.mediaID
+
+			return true
+		}
+
+		return false
+	}
+
+	var unmatched []supervisorTrack
+
+	for _, track := range tracks {
+		track := track
+
+		ok := match(track, func(item *SdpItem, index int) bool {
+			return controlPath(item.URL) == track.path
+		})
+		if !ok {
+			unmatched = append(unmatched, track)
+		}
+	}
+
+	for _, track := range unmatched {
+		track := track
+
+		match(track, func(item *SdpItem, index int) bool {
+			return item.Type == track.kind && index == track.index
+		})
+	}
+
+	return result
+}
+
+// supervisorHandler translates media ID in the current session
+// to the media ID in the first session
+type supervisorHandler struct {
+	handler  MediaHandler
+	mediaIDs map[int]int
+	received atomic.Bool
+}
+
+func (h *supervisorHandler) OnRTP(mediaID int, packet []byte) {
+	if id, ok := h.mediaIDs[mediaID]; ok {
+		h.received.Store(true)
+		h.handler.OnRTP(id, packet)
+	}
+}
+
+func (h *supervisorHandler) OnRTPPacket(mediaID int, packet *RTPPacket) {
+	if id, ok := h.mediaIDs[mediaID]; ok {
+		h.received.Store(true)
+		forwardRTP(h.handler, id, packet)
+	}
+}
+
+func (h *supervisorHandler) unwrap() MediaHandler {
+	return h.handler
+}
+
+func (h *supervisorHandler) OnRTCP(mediaID int, packet []byte) {
+	if id, ok := h.mediaIDs[mediaID]; ok {
+		h.handler.OnRTCP(id, packet)
+	}
+}
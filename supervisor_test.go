@@ -0,0 +1,235 @@
+package rtsp
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testListener keeps accepted connections to break them in tests
+type testListener struct {
+	net.Listener
+	conns chan net.Conn
+}
+
+func (l *testListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.conns <- conn
+	}
+
+	return conn, err
+}
+
+type testSupervisorHandler struct {
+	testClientHandler
+	discontinuity chan error
+}
+
+func (h *testSupervisorHandler) OnDiscontinuity(err error) {
+	h.discontinuity <- err
+}
+
+func TestSupervisor_backoff(t *testing.T) {
+	assert := assert.New(t)
+
+	s := &Supervisor{
+		MinBackoff: time.Second,
+		MaxBackoff: 10 * time.Second,
+	}
+
+	for attempt, expected := range []time.Duration{
+		time.Second,
+		2 * time.Second,
+		4 * time.Second,
+		8 * time.Second,
+		10 * time.Second,
+		10 * time.Second,
+	} {
+		delay := s.backoff(attempt)
+		assert.GreaterOrEqual(delay, expected/2)
+		assert.LessOrEqual(delay, expected)
+	}
+}
+
+func TestSupervisor_Run(t *testing.T) {
+	assert := assert.New(t)
+
+	h := &testServerHandler{
+		items: []*SdpItem{
+			{
+				Type:   "video",
+				Format: 96,
+				Media:  NewMediaH264(90000),
+			},
+			{
+				Type:   "audio",
+				Format: 97,
+				Media:  NewMediaMPEG4(48000),
+			},
+		},
+		teardown: make(chan *ServerSession, 2),
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(err) {
+		return
+	}
+
+	l := &testListener{
+		Listener: listener,
+		conns:    make(chan net.Conn, 4),
+	}
+
+	s := &Server{Handler: h}
+	go s.Serve(l)
+	defer s.Close()
+
+	u, _ := url.Parse("rtsp://" + listener.Addr().String() + "/live")
+	supervisor := NewSupervisor(&Client{
+		URL:    u,
+		UseTCP: true,
+	})
+	supervisor.MinBackoff = 10 * time.Millisecond
+	// only video track
+	supervisor.Select = func(item *SdpItem) bool {
+		return item.Type == "video"
+	}
+
+	handler := &testSupervisorHandler{
+		testClientHandler: testClientHandler{
+			packets: make(chan []byte, 1),
+		},
+		discontinuity: make(chan error, 1),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runErrorCh := make(chan error, 1)
+	go func() {
+		runErrorCh <- supervisor.Run(ctx, handler)
+	}()
+
+	select {
+	case <-handler.packets:
+	case <-time.After(time.Second):
+		assert.Fail("timeout")
+		return
+	}
+
+	assert.Len(supervisor.GetSDP(), 2)
+
+	// camera reboot
+	(<-l.conns).Close()
+
+	select {
+	case err := <-handler.discontinuity:
+		assert.Error(err)
+	case <-time.After(time.Second):
+		assert.Fail("timeout")
+		return
+	}
+
+	// drop packets from the first session
+	for len(handler.packets) > 0 {
+		<-handler.packets
+	}
+
+	select {
+	case packet := <-handler.packets:
+		assert.Equal([]byte{0x80, 0x60, 0x00, 0x01}, packet)
+	case <-time.After(time.Second):
+		assert.Fail("timeout")
+	}
+
+	assert.Len(l.conns, 1)
+
+	cancel()
+	assert.NoError(<-runErrorCh)
+}
+
+func TestSupervisor_matchTracks(t *testing.T) {
+	assert := assert.New(t)
+
+	item := func(kind, control string) *SdpItem {
+		u, _ := url.Parse(control)
+		return &SdpItem{Type: kind, URL: u, Media: NewMediaH264(90000)}
+	}
+
+	first := []*SdpItem{
+		item("video", "rtsp://10.0.0.1/live/trackID=0"),
+		item("audio", "rtsp://10.0.0.1/live/trackID=1"),
+		item("video", "rtsp://10.0.0.1/live/trackID=2"),
+	}
+	tracks := selectTracks(first, func(item *SdpItem) bool {
+		return item.Type == "video"
+	})
+
+	// same tracks
+	assert.Equal(map[int]int{0: 0, 2: 2}, matchTracks(tracks, first))
+
+	// new host and order
+	assert.Equal(
+		map[int]int{0: 2, 2: 0},
+		matchTracks(tracks, []*SdpItem{
+			item("video", "rtsp://10.0.0.2/live/trackID=2"),
+			item("audio", "rtsp://10.0.0.2/live/trackID=1"),
+			item("video", "rtsp://10.0.0.2/live/trackID=0"),
+		}),
+	)
+
+	// new Content-Base, matched by media type and order
+	assert.Equal(
+		map[int]int{1: 0, 2: 2},
+		matchTracks(tracks, []*SdpItem{
+			item("audio", "rtsp://10.0.0.2/stream/a"),
+			item("video", "rtsp://10.0.0.2/stream/v0"),
+			item("video", "rtsp://10.0.0.2/stream/v1"),
+		}),
+	)
+}
+
+func TestSupervisor_Terminal(t *testing.T) {
+	assert := assert.New(t)
+
+	requests := make(chan string, 4)
+
+	addr, closeServer := testServer(
+		func(ctx context.Context, r *Request, w *bufio.Writer) {
+			requests <- r.Method
+
+			if r.Method == MethodDescribe {
+				w.WriteString("RTSP/1.0 404 Not Found\r\n")
+			} else {
+				w.WriteString("RTSP/1.0 200 OK\r\n")
+			}
+			w.WriteString("CSeq: " + r.Header.Get("CSeq") + "\r\n")
+			w.WriteString("\r\n")
+			w.Flush()
+		},
+	)
+	defer closeServer()
+
+	u, _ := url.Parse("rtsp://" + addr + "/live")
+	supervisor := NewSupervisor(&Client{URL: u})
+	supervisor.MinBackoff = 10 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var statusErr *StatusError
+	if assert.ErrorAs(supervisor.Run(ctx, testHandler{}), &statusErr) {
+		assert.Equal(StatusNotFound, statusErr.Code)
+	}
+
+	// no reconnection
+	assert.Equal(MethodOptions, <-requests)
+	assert.Equal(MethodDescribe, <-requests)
+	assert.Len(requests, 0)
+}
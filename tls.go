@@ -0,0 +1,90 @@
+package rtsp
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+)
+
+// Default ports for URL schemes
+const (
+	defaultPort    = "554"
+	defaultTLSPort = "322"
+)
+
+var ErrCertificateNotPinned = fmt.Errorf("certificate is not pinned")
+
+// hostPort returns URL host with default port for the URL scheme
+func hostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+
+	port := defaultPort
+	if u.Scheme == "rtsps" {
+		port = defaultTLSPort
+	}
+
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// dial opens connection to the URL host.
+// For rtsps:// scheme connection is secured with TLS
+func dial(ctx context.Context, dialer *net.Dialer, u *url.URL, config *tls.Config) (net.Conn, error) {
+	addr := hostPort(u)
+
+	if u.Scheme != "rtsps" {
+		return dialer.DialContext(ctx, "tcp", addr)
+	}
+
+	if config == nil {
+		config = &tls.Config{}
+	} else {
+		config = config.Clone()
+	}
+
+	// Server Name Indication
+	if config.ServerName == "" {
+		config.ServerName = u.Hostname()
+	}
+
+	tlsDialer := &tls.Dialer{
+		NetDialer: dialer,
+		Config:    config,
+	}
+
+	return tlsDialer.DialContext(ctx, "tcp", addr)
+}
+
+// PublicKeyHash returns SHA-256 hash of the certificate public key
+// for certificate pinning with PinPublicKeys
+func PublicKeyHash(cert *x509.Certificate) []byte {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return sum[:]
+}
+
+// PinPublicKeys returns function for the tls.Config VerifyConnection
+// to check that the server certificate has one of the pinned public keys.
+// Hashes are SHA-256 of the certificate public key, see PublicKeyHash.
+// Works with InsecureSkipVerify for self-signed certificates
+func PinPublicKeys(hashes ...[]byte) func(tls.ConnectionState) error {
+	return func(state tls.ConnectionState) error {
+		if len(state.PeerCertificates) == 0 {
+			return ErrCertificateNotPinned
+		}
+
+		hash := PublicKeyHash(state.PeerCertificates[0])
+		for _, pinned := range hashes {
+			if bytes.Equal(hash, pinned) {
+				return nil
+			}
+		}
+
+		return ErrCertificateNotPinned
+	}
+}
@@ -0,0 +1,183 @@
+package rtsp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "camera.local"},
+		DNSNames:     []string{"camera.local"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+		Leaf:        leaf,
+	}, nil
+}
+
+func TestTLS_hostPort(t *testing.T) {
+	assert := assert.New(t)
+
+	u, _ := url.Parse("rtsp://camera.local/live")
+	assert.Equal("camera.local:554", hostPort(u))
+
+	u, _ = url.Parse("rtsps://camera.local/live")
+	assert.Equal("camera.local:322", hostPort(u))
+
+	u, _ = url.Parse("rtsps://[::1]:8322/live")
+	assert.Equal("[::1]:8322", hostPort(u))
+}
+
+func startTestTLSServer(handler ServerHandler, cert tls.Certificate, serverName chan<- string) (*Server, string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, ""
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			select {
+			case serverName <- hello.ServerName:
+			default:
+			}
+			return nil, nil
+		},
+	}
+
+	s := &Server{Handler: handler}
+	go s.Serve(tls.NewListener(listener, config))
+
+	return s, listener.Addr().String()
+}
+
+func TestTLS_Play(t *testing.T) {
+	assert := assert.New(t)
+
+	cert, err := testCertificate()
+	if !assert.NoError(err) {
+		return
+	}
+
+	h := &testServerHandler{
+		items: []*SdpItem{
+			{
+				Type:      "video",
+				Transport: "RTP/SAVP",
+				Format:    96,
+				Media:     NewMediaH264(90000),
+			},
+		},
+		teardown: make(chan *ServerSession, 1),
+	}
+
+	serverName := make(chan string, 1)
+	s, addr := startTestTLSServer(h, cert, serverName)
+	defer s.Close()
+
+	_, port, _ := net.SplitHostPort(addr)
+	u, _ := url.Parse("rtsps://localhost:" + port + "/live")
+	c := &Client{
+		URL:    u,
+		UseTCP: true,
+		TLSConfig: &tls.Config{
+			InsecureSkipVerify: true,
+			VerifyConnection:   PinPublicKeys(PublicKeyHash(cert.Leaf)),
+		},
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if !assert.NoError(c.Start(ctx)) {
+		return
+	}
+	assert.Equal("localhost", <-serverName)
+
+	sdp := c.GetSDP()
+	if !assert.Len(sdp, 1) {
+		return
+	}
+	assert.Equal("RTP/SAVP", sdp[0].Transport)
+
+	if !assert.NoError(c.Setup(ctx, 0, sdp[0].URL)) {
+		return
+	}
+
+	handler := &testClientHandler{
+		packets: make(chan []byte, 1),
+	}
+
+	playErrorCh := make(chan error, 1)
+	go func() {
+		playErrorCh <- c.Play(ctx, handler)
+	}()
+
+	select {
+	case <-handler.packets:
+	case <-time.After(time.Second):
+		assert.Fail("timeout")
+	}
+
+	cancel()
+	assert.NoError(<-playErrorCh)
+}
+
+func TestTLS_PinPublicKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	cert, err := testCertificate()
+	if !assert.NoError(err) {
+		return
+	}
+
+	s, addr := startTestTLSServer(&testServerHandler{}, cert, nil)
+	defer s.Close()
+
+	u, _ := url.Parse("rtsps://" + addr + "/live")
+	c := &Client{
+		URL: u,
+		TLSConfig: &tls.Config{
+			InsecureSkipVerify: true,
+			VerifyConnection:   PinPublicKeys(make([]byte, sha256.Size)),
+		},
+	}
+	defer c.Close()
+
+	assert.ErrorIs(c.Start(context.Background()), ErrCertificateNotPinned)
+}
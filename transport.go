@@ -1,10 +1,118 @@
 package rtsp
 
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
 type MediaHandler interface {
 	OnRTP(mediaID int, packet []byte)
 	OnRTCP(mediaID int, packet []byte)
 }
 
+// RTPPacketHandler is the optional MediaHandler interface
+// to receive parsed RTP packets. OnRTP is not called if implemented.
+// Packet is valid only during the call, invalid packets are dropped
+type RTPPacketHandler interface {
+	OnRTPPacket(mediaID int, packet *RTPPacket)
+}
+
+// handlerWrapper is the MediaHandler wrapping the user handler
+type handlerWrapper interface {
+	unwrap() MediaHandler
+}
+
+// wantsRTPPacket checks if the handler or the wrapped handler
+// implements RTPPacketHandler
+func wantsRTPPacket(handler MediaHandler) bool {
+	for {
+		w, ok := handler.(handlerWrapper)
+		if !ok {
+			break
+		}
+		handler = w.unwrap()
+	}
+
+	_, ok := handler.(RTPPacketHandler)
+
+	return ok
+}
+
+// rtpReceiver delivers RTP packets to the media handler.
+// Packet is parsed into the same RTPPacket to avoid allocations,
+// so every reader should have own receiver
+type rtpReceiver struct {
+	packet RTPPacket
+	parsed bool
+}
+
+// parse parses packet if handler requires parsed packets.
+// Returns false if packet is not valid
+func (r *rtpReceiver) parse(handler MediaHandler, data []byte) bool {
+	r.parsed = wantsRTPPacket(handler)
+	if !r.parsed {
+		return true
+	}
+
+	return r.packet.Unmarshal(data) == nil
+}
+
+// deliver sends the packet to the handler after parse
+func (r *rtpReceiver) deliver(handler MediaHandler, mediaID int, data []byte) {
+	if h, ok := handler.(RTPPacketHandler); ok && r.parsed {
+		h.OnRTPPacket(mediaID, &r.packet)
+	} else {
+		handler.OnRTP(mediaID, data)
+	}
+}
+
+// forwardRTP sends the parsed packet from the wrapper to the handler
+func forwardRTP(handler MediaHandler, mediaID int, packet *RTPPacket) {
+	if h, ok := handler.(RTPPacketHandler); ok {
+		h.OnRTPPacket(mediaID, packet)
+	} else {
+		handler.OnRTP(mediaID, packet.raw)
+	}
+}
+
+// ErrEndOfStream is returned by the transport on RTCP BYE
+// if EndOnBye is enabled
+var ErrEndOfStream = fmt.Errorf("end of stream")
+
+// byeWatch is the media handler to stop the transport on RTCP BYE
+type byeWatch struct {
+	MediaHandler
+	onError func(error)
+}
+
+func (w *byeWatch) OnRTCP(mediaID int, packet []byte) {
+	w.MediaHandler.OnRTCP(mediaID, packet)
+
+	if hasRTCPBye(packet) {
+		w.onError(fmt.Errorf("%w: rtcp bye on media %d", ErrEndOfStream, mediaID))
+	}
+}
+
+func (w *byeWatch) OnRTPPacket(mediaID int, packet *RTPPacket) {
+	forwardRTP(w.MediaHandler, mediaID, packet)
+}
+
+func (w *byeWatch) unwrap() MediaHandler {
+	return w.MediaHandler
+}
+
+// InterleavedConn is the RTSP connection shared with the interleaved transport
+type InterleavedConn interface {
+	// WriteInterleaved sends RTP or RTCP packet in the interleaved binary frame
+	WriteInterleaved(channel int, packet []byte) error
+	// ReadMessage reads RTSP request or response from the connection
+	// when next data is not the interleaved binary frame
+	ReadMessage(reader *bufio.Reader) error
+}
+
 type Transport interface {
 	// Setup configures the transport.
 	// Returns the transport protocol header for RTSP SETUP request
@@ -16,3 +124,74 @@ type Transport interface {
 	// Err returns the transport error
 	Err() <-chan error
 }
+
+// TransportConfigurer is the optional Transport interface
+// to apply the transport header from RTSP SETUP response
+type TransportConfigurer interface {
+	Configure(mediaID int, transport *TransportHeader) error
+}
+
+// TransportWriter is the optional Transport interface
+// to send packets to the server
+type TransportWriter interface {
+	// WriteRTP sends RTP packet to the server
+	WriteRTP(mediaID int, packet []byte) error
+	// WriteRTCP sends RTCP packet to the server
+	WriteRTCP(mediaID int, packet []byte) error
+}
+
+// TransportStatsProvider is the optional Transport interface
+// to get the packet counters
+type TransportStatsProvider interface {
+	Stats() TransportStats
+}
+
+// TransportStats is the packet counters of the transport
+type TransportStats struct {
+	// RTPPackets is the number of received RTP packets
+	RTPPackets uint64
+	// RTCPPackets is the number of received RTCP packets
+	RTCPPackets uint64
+	// Dropped is the number of packets from unexpected sender,
+	// with unexpected SSRC, on unknown channel, or invalid RTP packets
+	// if handler implements RTPPacketHandler
+	Dropped uint64
+}
+
+// transportCounters is the packet counters shared between transport readers
+type transportCounters struct {
+	rtpPackets  atomic.Uint64
+	rtcpPackets atomic.Uint64
+	dropped     atomic.Uint64
+}
+
+func (c *transportCounters) stats() TransportStats {
+	return TransportStats{
+		RTPPackets:  c.rtpPackets.Load(),
+		RTCPPackets: c.rtcpPackets.Load(),
+		Dropped:     c.dropped.Load(),
+	}
+}
+
+// parsePortRange parses port or channel range like 5000-5001.
+// If second value is not defined returns first+1
+func parsePortRange(value string) (first, second int, err error) {
+	a, b, ok := strings.Cut(value, "-")
+
+	if first, err = strconv.Atoi(a); err != nil {
+		return 0, 0, fmt.Errorf("invalid range %q", value)
+	}
+
+	second = first + 1
+	if ok {
+		if second, err = strconv.Atoi(b); err != nil {
+			return 0, 0, fmt.Errorf("invalid range %q", value)
+		}
+	}
+
+	if first < 0 || second < 0 {
+		return 0, 0, fmt.Errorf("invalid range %q", value)
+	}
+
+	return first, second, nil
+}
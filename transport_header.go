@@ -0,0 +1,174 @@
+package rtsp
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// TransportHeader is a value of the Transport header
+// https://datatracker.ietf.org/doc/html/rfc2326#section-12.39
+type TransportHeader struct {
+	// Profile is the transport protocol like RTP/AVP or RTP/AVP/TCP
+	Profile string
+	// Multicast is set for multicast delivery, unicast otherwise
+	Multicast bool
+	// Destination is the multicast group or the unicast address.
+	// nil if not defined or not an IP address
+	Destination net.IP
+	// Source is the sender address. nil if not defined or not an IP address
+	Source net.IP
+	// Interleaved is the RTP and RTCP channels for interleaved transport
+	Interleaved [2]int
+	// TTL is the multicast time-to-live. 0 if not defined
+	TTL int
+	// Port is the RTP and RTCP ports for multicast. 0 if not defined
+	Port [2]int
+	// ClientPort is the RTP and RTCP ports on the client. 0 if not defined
+	ClientPort [2]int
+	// ServerPort is the RTP and RTCP ports on the server. 0 if not defined
+	ServerPort [2]int
+	// SSRC is the synchronization source of the RTP stream
+	SSRC uint32
+	// Mode is the method to be supported for this session, like PLAY or RECORD
+	Mode string
+
+	HasInterleaved bool
+	HasSSRC        bool
+}
+
+// ParseTransportHeader parses value of the Transport header.
+// If value contains several transports, returns the first one.
+// Invalid optional parameters like ttl or ssrc are ignored,
+// error is returned for invalid ports or channels
+func ParseTransportHeader(value string) (*TransportHeader, error) {
+	value, _, _ = strings.Cut(value, ",")
+	return parseTransportSpec(value)
+}
+
+// ParseTransportHeaders parses value of the Transport header
+// with list of transports offered by the client
+func ParseTransportHeaders(value string) ([]*TransportHeader, error) {
+	var result []*TransportHeader
+
+	for _, spec := range strings.Split(value, ",") {
+		t, err := parseTransportSpec(spec)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+
+	return result, nil
+}
+
+func parseTransportSpec(value string) (*TransportHeader, error) {
+	fields := strings.Split(value, ";")
+
+	t := &TransportHeader{
+		Profile: strings.ToUpper(strings.TrimSpace(fields[0])),
+	}
+
+	if t.Profile == "" {
+		return nil, fmt.Errorf("invalid transport %q", value)
+	}
+
+	var err error
+
+	for _, param := range fields[1:] {
+		key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+		value = strings.Trim(strings.TrimSpace(value), "\"")
+
+		switch strings.ToLower(key) {
+		case "unicast":
+			t.Multicast = false
+		case "multicast":
+			t.Multicast = true
+		case "destination":
+			t.Destination = net.ParseIP(value)
+		case "source":
+			t.Source = net.ParseIP(value)
+		case "interleaved":
+			t.Interleaved[0], t.Interleaved[1], err = parsePortRange(value)
+			t.HasInterleaved = true
+		case "ttl":
+			// optional parameter, ignored if invalid
+			if v, err := strconv.Atoi(value); err == nil && v >= 0 && v <= 255 {
+				t.TTL = v
+			}
+		case "port":
+			t.Port[0], t.Port[1], err = parsePortRange(value)
+		case "client_port":
+			t.ClientPort[0], t.ClientPort[1], err = parsePortRange(value)
+		case "server_port":
+			t.ServerPort[0], t.ServerPort[1], err = parsePortRange(value)
+		case "ssrc":
+			// optional parameter, ignored if invalid.
+			// Some servers send decimal or short values
+			if v, err := strconv.ParseUint(value, 16, 32); err == nil {
+				t.SSRC = uint32(v)
+				t.HasSSRC = true
+			}
+		case "mode":
+			t.Mode = value
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid transport parameter %q: %w", param, err)
+		}
+	}
+
+	return t, nil
+}
+
+// String returns value for the Transport header
+func (t *TransportHeader) String() string {
+	var b strings.Builder
+
+	b.WriteString(t.Profile)
+
+	if t.Multicast {
+		b.WriteString(";multicast")
+	} else {
+		b.WriteString(";unicast")
+	}
+
+	if t.Destination != nil {
+		b.WriteString(";destination=" + t.Destination.String())
+	}
+
+	if t.Source != nil {
+		b.WriteString(";source=" + t.Source.String())
+	}
+
+	if t.HasInterleaved {
+		fmt.Fprintf(&b, ";interleaved=%d-%d", t.Interleaved[0], t.Interleaved[1])
+	}
+
+	if t.TTL != 0 {
+		fmt.Fprintf(&b, ";ttl=%d", t.TTL)
+	}
+
+	if t.Port[0] != 0 {
+		fmt.Fprintf(&b, ";port=%d-%d", t.Port[0], t.Port[1])
+	}
+
+	if t.ClientPort[0] != 0 {
+		fmt.Fprintf(&b, ";client_port=%d-%d", t.ClientPort[0], t.ClientPort[1])
+	}
+
+	if t.ServerPort[0] != 0 {
+		fmt.Fprintf(&b, ";server_port=%d-%d", t.ServerPort[0], t.ServerPort[1])
+	}
+
+	if t.HasSSRC {
+		fmt.Fprintf(&b, ";ssrc=%08X", t.SSRC)
+	}
+
+	if t.Mode != "" {
+		b.WriteString(";mode=" + t.Mode)
+	}
+
+	return b.String()
+}
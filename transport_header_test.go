@@ -0,0 +1,96 @@
+package rtsp
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransportHeader_Parse(t *testing.T) {
+	assert := assert.New(t)
+
+	transport, err := ParseTransportHeader(
+		`RTP/AVP;multicast;destination=232.0.0.1;source=192.168.1.10;` +
+			`port=5000-5001;ttl=16;ssrc=0000ABCD;mode="PLAY"`,
+	)
+	if assert.NoError(err) {
+		assert.Equal(&TransportHeader{
+			Profile:     "RTP/AVP",
+			Multicast:   true,
+			Destination: net.ParseIP("232.0.0.1"),
+			Source:      net.ParseIP("192.168.1.10"),
+			Port:        [2]int{5000, 5001},
+			TTL:         16,
+			SSRC:        0xABCD,
+			Mode:        "PLAY",
+			HasSSRC:     true,
+		}, transport)
+	}
+
+	transport, err = ParseTransportHeader(
+		"rtp/avp/tcp;unicast;interleaved=2-3, RTP/AVP;unicast;client_port=6000-6001",
+	)
+	if assert.NoError(err) {
+		assert.Equal("RTP/AVP/TCP", transport.Profile)
+		assert.False(transport.Multicast)
+		assert.True(transport.HasInterleaved)
+		assert.Equal([2]int{2, 3}, transport.Interleaved)
+	}
+
+	list, err := ParseTransportHeaders(
+		"RTP/AVP/TCP;unicast;interleaved=0, RTP/AVP;unicast;client_port=6000-6001;server_port=7000",
+	)
+	if assert.NoError(err) && assert.Len(list, 2) {
+		assert.Equal([2]int{0, 1}, list[0].Interleaved)
+		assert.Equal([2]int{6000, 6001}, list[1].ClientPort)
+		assert.Equal([2]int{7000, 7001}, list[1].ServerPort)
+	}
+
+	for _, value := range []string{
+		"",
+		"RTP/AVP;client_port=a-b",
+		"RTP/AVP;server_port=7000-x",
+		"RTP/AVP/TCP;interleaved=a",
+	} {
+		_, err := ParseTransportHeader(value)
+		assert.Error(err, value)
+	}
+
+	// invalid optional parameters are ignored
+	for _, value := range []string{
+		"RTP/AVP;unicast;server_port=7000-7001;ttl=256",
+		"RTP/AVP;unicast;server_port=7000-7001;ttl=abc",
+		"RTP/AVP;unicast;server_port=7000-7001;ssrc=XYZ",
+		"RTP/AVP;unicast;server_port=7000-7001;ssrc=3735928559",
+	} {
+		transport, err := ParseTransportHeader(value)
+		if assert.NoError(err, value) {
+			assert.Equal([2]int{7000, 7001}, transport.ServerPort, value)
+			assert.Equal(0, transport.TTL, value)
+			assert.False(transport.HasSSRC, value)
+		}
+	}
+
+	// short value
+	transport, err = ParseTransportHeader("RTP/AVP;unicast;ssrc=ABCDEF")
+	if assert.NoError(err) {
+		assert.True(transport.HasSSRC)
+		assert.Equal(uint32(0xABCDEF), transport.SSRC)
+	}
+}
+
+func TestTransportHeader_String(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, value := range []string{
+		"RTP/AVP;unicast;client_port=6000-6001;server_port=7000-7001;ssrc=0000ABCD",
+		"RTP/AVP/TCP;unicast;interleaved=0-1;mode=record",
+		"RTP/AVP;multicast;destination=232.0.0.1;source=192.168.1.10;ttl=16;port=5000-5001",
+	} {
+		transport, err := ParseTransportHeader(value)
+		if assert.NoError(err) {
+			assert.Equal(value, transport.String())
+		}
+	}
+}
@@ -5,66 +5,231 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"sync"
+	"syscall"
+	"time"
 )
 
+var ErrNoInterleavedConn = fmt.Errorf("interleaved connection is not defined")
+
 const (
 	interleavedPacketSize = 0x10000
 	interleavedHeaderSize = 4
 )
 
+// TCPConfig is the socket options for the RTSP connection
+// and the interleaved transport
+type TCPConfig struct {
+	// LocalIP is the local address to bind socket. Default is any address
+	LocalIP net.IP
+	// Interface is the network interface to bind socket.
+	// Socket is bound to the first interface address if LocalIP is not defined
+	Interface *net.Interface
+
+	// ReadBuffer is the socket receive buffer size (SO_RCVBUF).
+	// System default if 0
+	ReadBuffer int
+	// DSCP is the Differentiated Services Code Point for outgoing packets.
+	// Not set if 0
+	DSCP int
+}
+
+// dialer returns dialer with local address and socket options
+func (c *TCPConfig) dialer(timeout time.Duration) (*net.Dialer, error) {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+	}
+
+	localIP, err := bindIP(c.LocalIP, c.Interface)
+	if err != nil {
+		return nil, err
+	}
+
+	if localIP != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: localIP}
+	}
+
+	tos, err := dscpTOS(c.DSCP)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.ReadBuffer != 0 || tos != 0 {
+		dialer.Control = func(network, address string, rc syscall.RawConn) error {
+			return setSocketOptions(network, rc, c.ReadBuffer, tos)
+		}
+	}
+
+	return dialer, nil
+}
+
+// apply sets socket options for the accepted connection.
+// LocalIP and Interface are not used
+func (c *TCPConfig) apply(conn net.Conn) error {
+	tos, err := dscpTOS(c.DSCP)
+	if err != nil {
+		return err
+	}
+
+	if c.ReadBuffer == 0 && tos == 0 {
+		return nil
+	}
+
+	tcpConn, ok := conn.(*net.TCPConn)
+	if !ok {
+		return nil
+	}
+
+	rc, err := tcpConn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	network := "tcp4"
+	if addr, ok := conn.LocalAddr().(*net.TCPAddr); ok && addr.IP.To4() == nil {
+		network = "tcp6"
+	}
+
+	return setSocketOptions(network, rc, c.ReadBuffer, tos)
+}
+
+// writeInterleaved writes packet in the interleaved binary frame and flushes writer
+func writeInterleaved(w *bufio.Writer, channel int, packet []byte) error {
+	if len(packet) >= interleavedPacketSize {
+		return fmt.Errorf("interleaved packet too large %d", len(packet))
+	}
+
+	header := [interleavedHeaderSize]byte{'$', byte(channel)}
+	binary.BigEndian.PutUint16(header[2:], uint16(len(packet)))
+
+	if _, err := w.Write(header[:]); err != nil {
+		return err
+	}
+
+	if _, err := w.Write(packet); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+// readInterleaved reads the interleaved binary frame into buf.
+// buf should be at least interleavedPacketSize bytes
+func readInterleaved(r *bufio.Reader, buf []byte) (channel int, packet []byte, err error) {
+	header := buf[:interleavedHeaderSize]
+	if _, err = io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+
+	if header[0] != '$' {
+		return 0, nil, fmt.Errorf("invalid interleved header")
+	}
+
+	channel = int(header[1])
+	size := int(binary.BigEndian.Uint16(header[2:4]))
+
+	packet = buf[:size]
+	if _, err = io.ReadFull(r, packet); err != nil {
+		return 0, nil, err
+	}
+
+	return channel, packet, nil
+}
+
+// tcpChannel is the media stream for the interleaved channel
+type tcpChannel struct {
+	mediaID int
+	rtcp    bool
+}
+
 type TransportTCP struct {
 	reader    *bufio.Reader
+	conn      InterleavedConn
 	onceError sync.Once
 	err       chan error
+
+	lock sync.RWMutex
+	// channels maps interleaved channel to the media stream
+	channels map[int]tcpChannel
+	// medias maps media stream to the RTP and RTCP channels
+	medias map[int][2]int
+
+	counters transportCounters
+
+	// EndOnBye stops the transport with ErrEndOfStream on RTCP BYE
+	EndOnBye bool
+	// ReceiverReports sends RTCP receiver reports with SDES CNAME
+	// on the RTCP channel
+	ReceiverReports bool
+
+	reporter  *rtcpReporter
+	reporting sync.WaitGroup
+	onceClose sync.Once
+	done      chan struct{}
 }
 
+// NewTransportTCP makes a new interleaved transport reading the connection.
+// Packets could not be sent and RTSP messages are not expected
 func NewTransportTCP(reader *bufio.Reader) *TransportTCP {
+	return NewTransportTCPWithConn(reader, nil)
+}
+
+// NewTransportTCPWithConn makes a new interleaved transport
+// sharing the RTSP connection. RTSP messages between binary frames
+// are delivered to conn, packets are sent with conn
+func NewTransportTCPWithConn(reader *bufio.Reader, conn InterleavedConn) *TransportTCP {
 	return &TransportTCP{
-		reader: reader,
-		err:    make(chan error, 1),
+		reader:   reader,
+		conn:     conn,
+		err:      make(chan error, 1),
+		channels: make(map[int]tcpChannel),
+		medias:   make(map[int][2]int),
+		reporter: newRTCPReporter(),
+		done:     make(chan struct{}),
 	}
 }
 
+// loop reads the connection.
+// Interleaved binary frames are delivered to the media handler,
+// RTSP messages are delivered to the connection.
 func (t *TransportTCP) loop(wg *sync.WaitGroup, handler MediaHandler, onError func(error)) {
 	defer wg.Done()
 
 	buf := make([]byte, interleavedPacketSize)
-
-	var (
-		size, skip  int
-		transportID int
-	)
+	var rtp rtpReceiver
 
 	for {
-		if size == 0 {
-			n, err := t.reader.Read(buf[skip:interleavedHeaderSize])
-			if err != nil {
-				if errors.Is(err, net.ErrClosed) {
-					return
-				}
+		b, err := t.reader.Peek(1)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
 
-				onError(fmt.Errorf("read interleved header: %w", err))
+			onError(fmt.Errorf("read interleved header: %w", err))
+			return
+		}
+
+		if b[0] != '$' {
+			if t.conn == nil {
+				onError(fmt.Errorf("invalid interleved header"))
 				return
 			}
-			skip += n
 
-			if skip == interleavedHeaderSize {
-				if buf[0] != '$' {
-					onError(fmt.Errorf("invalid interleved header"))
+			if err := t.conn.ReadMessage(t.reader); err != nil {
+				if errors.Is(err, net.ErrClosed) {
 					return
 				}
 
-				transportID = int(buf[1])
-				size = int(binary.BigEndian.Uint16(buf[2:4]))
-				skip = 0
+				onError(fmt.Errorf("read message: %w", err))
+				return
 			}
 
 			continue
 		}
 
-		n, err := t.reader.Read(buf[skip:size])
+		transportID, packet, err := readInterleaved(t.reader, buf)
 		if err != nil {
 			if errors.Is(err, net.ErrClosed) {
 				return
@@ -73,42 +238,106 @@ func (t *TransportTCP) loop(wg *sync.WaitGroup, handler MediaHandler, onError fu
 			onError(fmt.Errorf("read interleaved packet: %w", err))
 			return
 		}
-		skip += n
 
-		if skip == size {
-			id := transportID >> 1
-			packet := buf[:size]
+		t.lock.RLock()
+		ch, ok := t.channels[transportID]
+		t.lock.RUnlock()
 
-			if (transportID & 1) == 0 {
-				handler.OnRTP(id, packet)
-			} else {
-				handler.OnRTCP(id, packet)
-			}
+		if !ok || (!ch.rtcp && !rtp.parse(handler, packet)) {
+			// unknown channel or invalid packet
+			t.counters.dropped.Add(1)
+			continue
+		}
 
-			size = 0
-			skip = 0
+		if ch.rtcp {
+			t.counters.rtcpPackets.Add(1)
+			handler.OnRTCP(ch.mediaID, packet)
+		} else {
+			t.counters.rtpPackets.Add(1)
+			rtp.deliver(handler, ch.mediaID, packet)
 		}
 	}
 }
 
 // Setup prepares the transport and returns the transport parameters.
+// Requests channels 2*mediaID and 2*mediaID+1
 func (t *TransportTCP) Setup(mediaID int) (string, error) {
-	rtpId := mediaID * 2
-	rtcpId := rtpId + 1
+	rtpChannel := mediaID * 2
+	rtcpChannel := rtpChannel + 1
 
-	transport := fmt.Sprintf(
-		"RTP/AVP/TCP;unicast;interleaved=%d-%d",
-		rtpId,
-		rtcpId,
-	)
+	t.setChannels(mediaID, rtpChannel, rtcpChannel)
 
-	return transport, nil
+	transport := &TransportHeader{
+		Profile:        "RTP/AVP/TCP",
+		Interleaved:    [2]int{rtpChannel, rtcpChannel},
+		HasInterleaved: true,
+	}
+
+	return transport.String(), nil
+}
+
+// Configure applies the transport header from the SETUP response.
+// Maps channels chosen by the server to the media stream
+func (t *TransportTCP) Configure(mediaID int, transport *TransportHeader) error {
+	if !transport.HasInterleaved {
+		return nil
+	}
+
+	rtpChannel, rtcpChannel := transport.Interleaved[0], transport.Interleaved[1]
+	if rtpChannel > 255 || rtcpChannel > 255 {
+		return fmt.Errorf("invalid interleaved channels %d-%d", rtpChannel, rtcpChannel)
+	}
+
+	t.setChannels(mediaID, rtpChannel, rtcpChannel)
+
+	return nil
+}
+
+func (t *TransportTCP) setChannels(mediaID, rtpChannel, rtcpChannel int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if prev, ok := t.medias[mediaID]; ok {
+		delete(t.channels, prev[0])
+		delete(t.channels, prev[1])
+	}
+
+	t.medias[mediaID] = [2]int{rtpChannel, rtcpChannel}
+	t.channels[rtpChannel] = tcpChannel{mediaID: mediaID}
+	t.channels[rtcpChannel] = tcpChannel{mediaID: mediaID, rtcp: true}
+}
+
+// getChannels returns RTP and RTCP channels for the media stream
+func (t *TransportTCP) getChannels(mediaID int) ([2]int, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	channels, ok := t.medias[mediaID]
+	if !ok {
+		return channels, fmt.Errorf("media %d is not defined", mediaID)
+	}
+
+	return channels, nil
 }
 
 // Play starts receigin RTP/RTCP packets.
 func (t *TransportTCP) Play(handler MediaHandler) {
 	var wg sync.WaitGroup
 
+	if t.EndOnBye {
+		handler = &byeWatch{MediaHandler: handler, onError: t.onError}
+	}
+
+	if t.ReceiverReports {
+		handler = &reportWatch{MediaHandler: handler, reporter: t.reporter}
+
+		t.reporting.Add(1)
+		go func() {
+			defer t.reporting.Done()
+			t.reporter.run(t.done, t.WriteRTCP)
+		}()
+	}
+
 	wg.Add(1)
 	go t.loop(&wg, handler, t.onError)
 
@@ -118,7 +347,50 @@ func (t *TransportTCP) Play(handler MediaHandler) {
 	}()
 }
 
-func (t *TransportTCP) Close() {}
+// WriteRTP sends RTP packet in the interleaved frame.
+func (t *TransportTCP) WriteRTP(mediaID int, packet []byte) error {
+	channels, err := t.getChannels(mediaID)
+	if err != nil {
+		return err
+	}
+
+	if t.conn == nil {
+		return ErrNoInterleavedConn
+	}
+
+	return t.conn.WriteInterleaved(channels[0], packet)
+}
+
+// WriteRTCP sends RTCP packet in the interleaved frame.
+func (t *TransportTCP) WriteRTCP(mediaID int, packet []byte) error {
+	channels, err := t.getChannels(mediaID)
+	if err != nil {
+		return err
+	}
+
+	if t.conn == nil {
+		return ErrNoInterleavedConn
+	}
+
+	return t.conn.WriteInterleaved(channels[1], packet)
+}
+
+// SetClockRate defines RTP clock rate of the media
+// for the interarrival jitter in receiver reports. Default is 90000
+func (t *TransportTCP) SetClockRate(mediaID, clockRate int) {
+	t.reporter.setClockRate(mediaID, clockRate)
+}
+
+// Close stops the transport.
+// Returns after receiver reports are stopped,
+// so the connection could be replaced
+func (t *TransportTCP) Close() {
+	t.onceClose.Do(func() {
+		close(t.done)
+	})
+
+	t.reporting.Wait()
+}
 
 func (t *TransportTCP) onError(err error) {
 	t.onceError.Do(func() {
@@ -129,3 +401,9 @@ func (t *TransportTCP) onError(err error) {
 func (t *TransportTCP) Err() <-chan error {
 	return t.err
 }
+
+// Stats returns the packet counters.
+// Packets on unknown channels are dropped
+func (t *TransportTCP) Stats() TransportStats {
+	return t.counters.stats()
+}
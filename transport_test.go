@@ -0,0 +1,82 @@
+package rtsp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/ipv4"
+)
+
+func TestTransport_OptionalInterfaces(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, transport := range []Transport{
+		NewTransportTCP(nil),
+		NewTransportUDP(),
+		NewTransportUDPMulticast(nil),
+	} {
+		assert.Implements((*TransportConfigurer)(nil), transport)
+		assert.Implements((*TransportWriter)(nil), transport)
+		assert.Implements((*TransportStatsProvider)(nil), transport)
+		assert.Implements((*clockRateSetter)(nil), transport)
+	}
+}
+
+func TestTransport_TCPConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	localhost := net.IPv4(127, 0, 0, 1)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if !assert.NoError(err) {
+		return
+	}
+	defer listener.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	config := TCPConfig{
+		LocalIP:    localhost,
+		ReadBuffer: 0x40000,
+		DSCP:       46,
+	}
+
+	dialer, err := config.dialer(time.Second)
+	if !assert.NoError(err) {
+		return
+	}
+
+	conn, err := dialer.Dial("tcp", listener.Addr().String())
+	if !assert.NoError(err) {
+		return
+	}
+	defer conn.Close()
+
+	assert.True(localhost.Equal(conn.LocalAddr().(*net.TCPAddr).IP))
+
+	if tos, err := ipv4.NewConn(conn).TOS(); err == nil {
+		assert.Equal(46<<2, tos)
+	}
+
+	// accepted connection
+	serverConn := <-accepted
+	defer serverConn.Close()
+
+	if assert.NoError(config.apply(serverConn)) {
+		if tos, err := ipv4.NewConn(serverConn).TOS(); err == nil {
+			assert.Equal(46<<2, tos)
+		}
+	}
+
+	config.DSCP = 64
+	_, err = config.dialer(time.Second)
+	assert.Error(err)
+}
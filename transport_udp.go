@@ -1,35 +1,286 @@
 package rtsp
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"math/rand"
 	"net"
 	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+var (
+	ErrRemoteAddress = fmt.Errorf("remote address is not defined")
+	ErrNoFreePorts   = fmt.Errorf("no free ports")
+)
+
+// Default local port range for UDP transport
+const (
+	defaultMinPort = 10000
+	defaultMaxPort = 65000
 )
 
+// UDPConfig is the socket options for UDP transport
+type UDPConfig struct {
+	// MinPort and MaxPort is the range for local RTP and RTCP ports.
+	// Range is scanned from the random port. Default is 10000-65000
+	MinPort int
+	MaxPort int
+
+	// LocalIP is the local address to bind sockets. Default is any address
+	LocalIP net.IP
+	// Interface is the network interface to bind sockets.
+	// Sockets are bound to the first interface address if LocalIP is not defined
+	Interface *net.Interface
+
+	// ReadBuffer is the socket receive buffer size (SO_RCVBUF).
+	// System default if 0
+	ReadBuffer int
+	// DSCP is the Differentiated Services Code Point for outgoing packets.
+	// Not set if 0
+	DSCP int
+
+	// AnySource accepts packets from any sender.
+	// By default packets are accepted only from the server address and ports
+	// defined in the SETUP response
+	AnySource bool
+	// FilterSSRC drops RTP packets with synchronization source
+	// other than ssrc parameter in the SETUP response.
+	// Disabled by default, some servers send packets with other SSRC
+	FilterSSRC bool
+}
+
+// portRange returns range for the even RTP port
+func (c *UDPConfig) portRange() (minPort, maxPort int, err error) {
+	minPort, maxPort = c.MinPort, c.MaxPort
+	if minPort == 0 {
+		minPort = defaultMinPort
+	}
+	if maxPort == 0 {
+		maxPort = defaultMaxPort
+	}
+
+	// RTP port should be even
+	minPort = (minPort + 1) &^ 1
+	// RTCP port is RTP port + 1
+	maxPort = (maxPort - 1) &^ 1
+
+	if minPort <= 0 || maxPort > 65535 || minPort > maxPort {
+		return 0, 0, fmt.Errorf("invalid port range %d-%d", c.MinPort, c.MaxPort)
+	}
+
+	return minPort, maxPort, nil
+}
+
+// localIP returns address to bind sockets
+func (c *UDPConfig) localIP() (net.IP, error) {
+	ip, err := bindIP(c.LocalIP, c.Interface)
+	if ip == nil && err == nil {
+		ip = net.IPv4zero
+	}
+
+	return ip, err
+}
+
+// bindIP returns address to bind sockets.
+// Returns nil if localIP and interface are not defined
+func bindIP(localIP net.IP, ifi *net.Interface) (net.IP, error) {
+	if localIP != nil {
+		return localIP, nil
+	}
+
+	if ifi == nil {
+		return nil, nil
+	}
+
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil, err
+	}
+
+	var result net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+
+		// prefer IPv4 address
+		if ipNet.IP.To4() != nil {
+			return ipNet.IP, nil
+		}
+
+		if result == nil {
+			result = ipNet.IP
+		}
+	}
+
+	if result == nil {
+		return nil, fmt.Errorf("interface %s has no address", ifi.Name)
+	}
+
+	return result, nil
+}
+
+// apply sets socket options
+func (c *UDPConfig) apply(udpConn *net.UDPConn) error {
+	if c.ReadBuffer != 0 {
+		if err := udpConn.SetReadBuffer(c.ReadBuffer); err != nil {
+			return fmt.Errorf("set read buffer: %w", err)
+		}
+	}
+
+	tos, err := dscpTOS(c.DSCP)
+	if err != nil || tos == 0 {
+		return err
+	}
+
+	localIP := udpConn.LocalAddr().(*net.UDPAddr).IP
+	if localIP.To4() != nil {
+		err = ipv4.NewConn(udpConn).SetTOS(tos)
+	} else {
+		err = ipv6.NewConn(udpConn).SetTrafficClass(tos)
+		if err == nil && localIP.IsUnspecified() {
+			// dual-stack socket sends IPv4 packets with type of service.
+			// Not supported for IPv6 sockets on some platforms
+			_ = ipv4.NewConn(udpConn).SetTOS(tos)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("set dscp: %w", err)
+	}
+
+	return nil
+}
+
+// dscpTOS returns type of service for the Differentiated Services Code Point
+func dscpTOS(dscp int) (int, error) {
+	if dscp < 0 || dscp > 63 {
+		return 0, fmt.Errorf("invalid dscp %d", dscp)
+	}
+
+	return dscp << 2, nil
+}
+
 type conn struct {
 	mediaID  int
 	rtpConn  *net.UDPConn
 	rtcpConn *net.UDPConn
+	rtpAddr  *net.UDPAddr
+	rtcpAddr *net.UDPAddr
 	lock     sync.Mutex
+
+	// rtpSender and rtcpSender is the expected source of packets.
+	// nil accepts packets from any source, port 0 accepts any port
+	rtpSender  *net.UDPAddr
+	rtcpSender *net.UDPAddr
+	// ssrc is the expected synchronization source of RTP packets
+	ssrc    uint32
+	hasSSRC bool
+
+	counters *transportCounters
+	// received is set on first accepted packet
+	received atomic.Bool
+}
+
+// setFilter defines the expected source of packets.
+// Port 0 accepts packets from any port
+func (c *conn) setFilter(ip net.IP, rtpPort, rtcpPort int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.rtpSender = &net.UDPAddr{IP: ip, Port: rtpPort}
+	c.rtcpSender = &net.UDPAddr{IP: ip, Port: rtcpPort}
+}
+
+// setSSRC defines the expected synchronization source of RTP packets
+func (c *conn) setSSRC(ssrc uint32) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.ssrc = ssrc
+	c.hasSSRC = true
+}
+
+// accept checks that packet is received from the expected source.
+// valid is false if packet is rejected by the parser.
+// Counts received and dropped packets
+func (c *conn) accept(rtcp bool, addr *net.UDPAddr, packet []byte, valid bool) bool {
+	c.lock.Lock()
+	sender := c.rtpSender
+	if rtcp {
+		sender = c.rtcpSender
+	}
+	ssrc, hasSSRC := c.ssrc, c.hasSSRC
+	c.lock.Unlock()
+
+	ok := valid
+
+	if sender != nil {
+		if !sender.IP.Equal(addr.IP) {
+			ok = false
+		} else if sender.Port != 0 && sender.Port != addr.Port {
+			ok = false
+		}
+	}
+
+	if ok && hasSSRC && !rtcp {
+		// SSRC is at offset 8 in the RTP header
+		if len(packet) < 12 || binary.BigEndian.Uint32(packet[8:12]) != ssrc {
+			ok = false
+		}
+	}
+
+	if ok {
+		c.received.Store(true)
+	}
+
+	if c.counters != nil {
+		switch {
+		case !ok:
+			c.counters.dropped.Add(1)
+		case rtcp:
+			c.counters.rtcpPackets.Add(1)
+		default:
+			c.counters.rtpPackets.Add(1)
+		}
+	}
+
+	return ok
 }
 
 func (c *conn) loopRTP(wg *sync.WaitGroup, handler MediaHandler, onError func(error)) {
 	defer wg.Done()
 
 	buf := make([]byte, 0x10000)
+	var rtp rtpReceiver
+
+	c.lock.Lock()
+	rtpConn := c.rtpConn
+	c.lock.Unlock()
+
+	if rtpConn == nil {
+		// transport closed before start
+		return
+	}
 
 	for {
-		n, _, err := c.rtpConn.ReadFromUDP(buf)
+		n, addr, err := rtpConn.ReadFromUDP(buf)
 		if err != nil {
 			if errors.Is(err, net.ErrClosed) {
 				return
 			}
 
 			c.lock.Lock()
-			c.rtpConn.Close()
-			c.rtpConn = nil
+			rtpConn.Close()
+			if c.rtpConn == rtpConn {
+				c.rtpConn = nil
+			}
 			c.lock.Unlock()
 
 			onError(fmt.Errorf("read rtp: %w", err))
@@ -37,7 +288,12 @@ func (c *conn) loopRTP(wg *sync.WaitGroup, handler MediaHandler, onError func(er
 			return
 		}
 
-		handler.OnRTP(c.mediaID, buf[:n])
+		valid := rtp.parse(handler, buf[:n])
+		if !c.accept(false, addr, buf[:n], valid) {
+			continue
+		}
+
+		rtp.deliver(handler, c.mediaID, buf[:n])
 	}
 }
 
@@ -46,16 +302,27 @@ func (c *conn) loopRTCP(wg *sync.WaitGroup, handler MediaHandler, onError func(e
 
 	buf := make([]byte, 0x800)
 
+	c.lock.Lock()
+	rtcpConn := c.rtcpConn
+	c.lock.Unlock()
+
+	if rtcpConn == nil {
+		// transport closed before start
+		return
+	}
+
 	for {
-		n, _, err := c.rtcpConn.ReadFromUDP(buf)
+		n, addr, err := rtcpConn.ReadFromUDP(buf)
 		if err != nil {
 			if errors.Is(err, net.ErrClosed) {
 				return
 			}
 
 			c.lock.Lock()
-			c.rtcpConn.Close()
-			c.rtcpConn = nil
+			rtcpConn.Close()
+			if c.rtcpConn == rtcpConn {
+				c.rtcpConn = nil
+			}
 			c.lock.Unlock()
 
 			onError(fmt.Errorf("read rtcp: %w", err))
@@ -63,10 +330,58 @@ func (c *conn) loopRTCP(wg *sync.WaitGroup, handler MediaHandler, onError func(e
 			return
 		}
 
+		if !c.accept(true, addr, buf[:n], true) {
+			continue
+		}
+
 		handler.OnRTCP(c.mediaID, buf[:n])
 	}
 }
 
+func (c *conn) write(rtcp bool, packet []byte) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	udpConn, addr := c.rtpConn, c.rtpAddr
+	if rtcp {
+		udpConn, addr = c.rtcpConn, c.rtcpAddr
+	}
+
+	if udpConn == nil {
+		return net.ErrClosed
+	}
+
+	if addr == nil {
+		return ErrRemoteAddress
+	}
+
+	_, err := udpConn.WriteToUDP(packet, addr)
+	return err
+}
+
+func (c *conn) localPorts() (rtpPort, rtcpPort int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.rtpConn != nil {
+		rtpPort = c.rtpConn.LocalAddr().(*net.UDPAddr).Port
+	}
+
+	if c.rtcpConn != nil {
+		rtcpPort = c.rtcpConn.LocalAddr().(*net.UDPAddr).Port
+	}
+
+	return
+}
+
+func (c *conn) setRemote(ip net.IP, rtpPort, rtcpPort int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.rtpAddr = &net.UDPAddr{IP: ip, Port: rtpPort}
+	c.rtcpAddr = &net.UDPAddr{IP: ip, Port: rtcpPort}
+}
+
 func (c *conn) start(wg *sync.WaitGroup, handler MediaHandler, onError func(error)) {
 	wg.Add(2)
 	go c.loopRTP(wg, handler, onError)
@@ -88,52 +403,180 @@ func (c *conn) close() {
 	}
 }
 
-type TransportUDP struct {
+// udpTransport is the common part of the unicast and multicast UDP transports
+type udpTransport struct {
 	connList  []*conn
 	onceClose sync.Once
 	onceError sync.Once
 	err       chan error
+	done      chan struct{}
+	counters  transportCounters
+
+	// EndOnBye stops the transport with ErrEndOfStream on RTCP BYE
+	EndOnBye bool
+	// ReceiverReports sends RTCP receiver reports with SDES CNAME
+	// to the RTCP port
+	ReceiverReports bool
+
+	reporter *rtcpReporter
+}
+
+func (t *udpTransport) getConn(mediaID int) *conn {
+	for _, c := range t.connList {
+		if c.mediaID == mediaID {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// Play starts receiving RTP/RTCP packets.
+func (t *udpTransport) Play(handler MediaHandler) {
+	var wg sync.WaitGroup
+
+	if t.EndOnBye {
+		handler = &byeWatch{MediaHandler: handler, onError: t.onError}
+	}
+
+	if t.ReceiverReports {
+		handler = &reportWatch{MediaHandler: handler, reporter: t.reporter}
+		go t.reporter.run(t.done, t.WriteRTCP)
+	}
+
+	for _, c := range t.connList {
+		c.start(&wg, handler, t.onError)
+	}
+
+	go func() {
+		wg.Wait()
+		t.onError(nil)
+	}()
+}
+
+// WriteRTP sends RTP packet to the server_port.
+func (t *udpTransport) WriteRTP(mediaID int, packet []byte) error {
+	c := t.getConn(mediaID)
+	if c == nil {
+		return fmt.Errorf("media %d is not defined", mediaID)
+	}
+
+	return c.write(false, packet)
+}
+
+// WriteRTCP sends RTCP packet to the server_port.
+func (t *udpTransport) WriteRTCP(mediaID int, packet []byte) error {
+	c := t.getConn(mediaID)
+	if c == nil {
+		return fmt.Errorf("media %d is not defined", mediaID)
+	}
+
+	return c.write(true, packet)
+}
+
+// SetClockRate defines RTP clock rate of the media
+// for the interarrival jitter in receiver reports. Default is 90000
+func (t *udpTransport) SetClockRate(mediaID, clockRate int) {
+	t.reporter.setClockRate(mediaID, clockRate)
+}
+
+func (t *udpTransport) Close() {
+	t.onceClose.Do(func() {
+		close(t.done)
+
+		for _, c := range t.connList {
+			c.close()
+		}
+	})
+}
+
+func (t *udpTransport) onError(err error) {
+	t.onceError.Do(func() {
+		t.err <- err
+	})
+}
+
+func (t *udpTransport) Err() <-chan error {
+	return t.err
+}
+
+// Stats returns the packet counters.
+// Packets from unexpected sender or with unexpected SSRC are dropped
+func (t *udpTransport) Stats() TransportStats {
+	return t.counters.stats()
 }
 
+type TransportUDP struct {
+	udpTransport
+	remoteIP net.IP
+
+	// Config is the socket options
+	Config UDPConfig
+
+	// HolePunchSchedule is the delays from the SETUP response
+	// to send dummy RTP and RTCP packets to the server_port.
+	// Packets open NAT mapping for the server packets.
+	// Sending stops when first packet is received from the server.
+	// nil disables hole punching
+	HolePunchSchedule []time.Duration
+}
+
+// NewTransportUDP makes a new UDP transport.
+// Server address is defined with source parameter in the SETUP response
 func NewTransportUDP() *TransportUDP {
+	return NewTransportUDPWithConfig(nil, UDPConfig{})
+}
+
+// NewTransportUDPWithConfig makes a new UDP transport with socket options.
+// remoteIP is the server address to send packets,
+// could be redefined with source parameter in the SETUP response
+func NewTransportUDPWithConfig(remoteIP net.IP, config UDPConfig) *TransportUDP {
 	return &TransportUDP{
-		err: make(chan error, 1),
+		udpTransport: udpTransport{
+			err:      make(chan error, 1),
+			done:     make(chan struct{}),
+			reporter: newRTCPReporter(),
+		},
+		remoteIP: remoteIP,
+		Config:   config,
 	}
 }
 
 // Setup prepares the transport and returns the transport parameters.
 func (t *TransportUDP) Setup(mediaID int) (string, error) {
-	a := &net.UDPAddr{
-		IP: net.IPv4zero,
+	minPort, maxPort, err := t.Config.portRange()
+	if err != nil {
+		return "", err
+	}
+
+	localIP, err := t.Config.localIP()
+	if err != nil {
+		return "", err
 	}
 
 	var (
-		err               error
 		rtpPort, rtcpPort int
 		rtpConn, rtcpConn *net.UDPConn
 	)
 
-	const (
-		minPort = 10000
-		maxPort = 65000
-	)
+	// scan port pairs from the random one
+	pairs := (maxPort-minPort)/2 + 1
+	offset := rand.Intn(pairs)
 
-	for {
-		// rtpPort random number in range 10000-65000
-		rtpPort = minPort + (rand.Intn(maxPort - minPort))
-		// rtpPort should be even
-		rtpPort &^= 1
+	for i := 0; ; i++ {
+		if i == pairs {
+			return "", fmt.Errorf("%w in range %d-%d", ErrNoFreePorts, minPort, maxPort+1)
+		}
 
+		rtpPort = minPort + (offset+i)%pairs*2
 		rtcpPort = rtpPort + 1
 
-		a.Port = rtpPort
-		rtpConn, err = net.ListenUDP("udp", a)
+		rtpConn, err = net.ListenUDP("udp", &net.UDPAddr{IP: localIP, Port: rtpPort})
 		if err != nil {
 			continue
 		}
 
-		a.Port = rtcpPort
-		rtcpConn, err = net.ListenUDP("udp", a)
+		rtcpConn, err = net.ListenUDP("udp", &net.UDPAddr{IP: localIP, Port: rtcpPort})
 		if err != nil {
 			rtpConn.Close()
 			continue
@@ -142,50 +585,104 @@ func (t *TransportUDP) Setup(mediaID int) (string, error) {
 		break
 	}
 
+	for _, udpConn := range []*net.UDPConn{rtpConn, rtcpConn} {
+		if err := t.Config.apply(udpConn); err != nil {
+			rtpConn.Close()
+			rtcpConn.Close()
+			return "", err
+		}
+	}
+
 	c := &conn{
 		mediaID:  mediaID,
 		rtpConn:  rtpConn,
 		rtcpConn: rtcpConn,
+		counters: &t.counters,
 	}
 	t.connList = append(t.connList, c)
 
-	transport := fmt.Sprintf(
-		"RTP/AVP;unicast;client_port=%d-%d",
-		rtpPort,
-		rtcpPort,
-	)
+	transport := &TransportHeader{
+		Profile:    "RTP/AVP",
+		ClientPort: [2]int{rtpPort, rtcpPort},
+	}
 
-	return transport, nil
+	return transport.String(), nil
 }
 
-// Play starts receigin RTP/RTCP packets.
-func (t *TransportUDP) Play(handler MediaHandler) {
-	var wg sync.WaitGroup
+// Configure applies the transport header from the SETUP response.
+// Defines the server address to send packets with server_port and source parameters.
+// Packets from other addresses are dropped unless Config.AnySource is set.
+// If Config.FilterSSRC is set and ssrc parameter is defined,
+// RTP packets with other synchronization source are dropped
+func (t *TransportUDP) Configure(mediaID int, transport *TransportHeader) error {
+	c := t.getConn(mediaID)
+	if c == nil {
+		return fmt.Errorf("media %d is not defined", mediaID)
+	}
 
-	for _, c := range t.connList {
-		c.start(&wg, handler, t.onError)
+	if transport.HasSSRC && t.Config.FilterSSRC {
+		c.setSSRC(transport.SSRC)
 	}
 
-	go func() {
-		wg.Wait()
-		t.onError(nil)
-	}()
+	remoteIP := t.remoteIP
+	if transport.Source != nil {
+		remoteIP = transport.Source
+	}
+
+	if remoteIP == nil {
+		return nil
+	}
+
+	rtpPort, rtcpPort := transport.ServerPort[0], transport.ServerPort[1]
+	if !t.Config.AnySource {
+		c.setFilter(remoteIP, rtpPort, rtcpPort)
+	}
+
+	if rtpPort == 0 {
+		return nil
+	}
+
+	c.setRemote(remoteIP, rtpPort, rtcpPort)
+
+	if len(t.HolePunchSchedule) != 0 {
+		go t.holePunch(c)
+	}
+
+	return nil
 }
 
-func (t *TransportUDP) Close() {
-	t.onceClose.Do(func() {
-		for _, c := range t.connList {
-			c.close()
+// Dummy packets for NAT hole punching.
+// RTP packet with empty payload and RTCP empty receiver report
+var (
+	holePunchRTP  = []byte{0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	holePunchRTCP = []byte{0x80, 0xC9, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00}
+)
+
+// holePunch sends dummy packets from the client ports to the server ports
+// with HolePunchSchedule
+func (t *TransportUDP) holePunch(c *conn) {
+	start := time.Now()
+
+	for _, delay := range t.HolePunchSchedule {
+		timer := time.NewTimer(time.Until(start.Add(delay)))
+
+		select {
+		case <-t.done:
+			timer.Stop()
+			return
+		case <-timer.C:
 		}
-	})
-}
 
-func (t *TransportUDP) onError(err error) {
-	t.onceError.Do(func() {
-		t.err <- err
-	})
-}
+		if c.received.Load() {
+			return
+		}
 
-func (t *TransportUDP) Err() <-chan error {
-	return t.err
+		if err := c.write(false, holePunchRTP); err != nil {
+			return
+		}
+
+		if err := c.write(true, holePunchRTCP); err != nil {
+			return
+		}
+	}
 }
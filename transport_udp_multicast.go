@@ -0,0 +1,176 @@
+package rtsp
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// TransportUDPMulticast receives RTP/RTCP packets from the multicast group
+// defined by the server in the SETUP response
+type TransportUDPMulticast struct {
+	udpTransport
+	ifi *net.Interface
+
+	// Config is the socket options.
+	// Ports and local address are defined by the server and ifi
+	Config UDPConfig
+}
+
+// NewTransportUDPMulticast makes a new multicast UDP transport.
+// ifi is the network interface to join multicast groups,
+// system default interface is used if nil
+func NewTransportUDPMulticast(ifi *net.Interface) *TransportUDPMulticast {
+	return &TransportUDPMulticast{
+		udpTransport: udpTransport{
+			err:      make(chan error, 1),
+			done:     make(chan struct{}),
+			reporter: newRTCPReporter(),
+		},
+		ifi: ifi,
+	}
+}
+
+// Setup returns the transport parameters to request multicast delivery.
+// Group and ports are defined by the server
+func (t *TransportUDPMulticast) Setup(mediaID int) (string, error) {
+	transport := &TransportHeader{
+		Profile:   "RTP/AVP",
+		Multicast: true,
+	}
+
+	return transport.String(), nil
+}
+
+// Configure applies the transport header from the SETUP response.
+// Joins the group defined with destination and port parameters.
+// If source parameter is defined, joins the source-specific group.
+// If Config.FilterSSRC is set and ssrc parameter is defined,
+// RTP packets with other synchronization source are dropped
+func (t *TransportUDPMulticast) Configure(mediaID int, transport *TransportHeader) error {
+	if t.getConn(mediaID) != nil {
+		return fmt.Errorf("media %d is already defined", mediaID)
+	}
+
+	if !transport.Multicast {
+		return fmt.Errorf("multicast is not supported by server")
+	}
+
+	group := transport.Destination
+	if group == nil || !group.IsMulticast() {
+		return fmt.Errorf("invalid multicast destination %v", group)
+	}
+
+	rtpPort, rtcpPort := transport.Port[0], transport.Port[1]
+	if rtpPort == 0 {
+		return fmt.Errorf("multicast port is not defined")
+	}
+
+	source, ttl := transport.Source, transport.TTL
+
+	rtpConn, err := listenMulticast(t.ifi, group, source, rtpPort, ttl)
+	if err != nil {
+		return err
+	}
+
+	rtcpConn, err := listenMulticast(t.ifi, group, source, rtcpPort, ttl)
+	if err != nil {
+		rtpConn.Close()
+		return err
+	}
+
+	for _, udpConn := range []*net.UDPConn{rtpConn, rtcpConn} {
+		if err := t.Config.apply(udpConn); err != nil {
+			rtpConn.Close()
+			rtcpConn.Close()
+			return err
+		}
+	}
+
+	c := &conn{
+		mediaID:  mediaID,
+		rtpConn:  rtpConn,
+		rtcpConn: rtcpConn,
+		counters: &t.counters,
+	}
+	// RTCP reports are sent to the group
+	c.setRemote(group, rtpPort, rtcpPort)
+
+	if source != nil && !t.Config.AnySource {
+		c.setFilter(source, 0, 0)
+	}
+
+	if transport.HasSSRC && t.Config.FilterSSRC {
+		c.setSSRC(transport.SSRC)
+	}
+	t.connList = append(t.connList, c)
+
+	return nil
+}
+
+// listenMulticast opens socket on the port and joins the group.
+// Port could be shared with other receivers on the same host,
+// socket receives only packets of the joined group.
+// ttl is the time-to-live for outgoing packets, system default if 0
+func listenMulticast(ifi *net.Interface, group, source net.IP, port, ttl int) (*net.UDPConn, error) {
+	network := "udp4"
+	if group.To4() == nil {
+		network = "udp6"
+	}
+
+	lc := net.ListenConfig{
+		Control: func(network, address string, c syscall.RawConn) error {
+			if err := setReuseAddr(network, address, c); err != nil {
+				return err
+			}
+			return setMulticastOnly(network, address, c)
+		},
+	}
+	packetConn, err := lc.ListenPacket(context.Background(), network, ":"+strconv.Itoa(port))
+	if err != nil {
+		return nil, err
+	}
+	udpConn := packetConn.(*net.UDPConn)
+
+	groupAddr := &net.UDPAddr{IP: group}
+
+	if network == "udp4" {
+		p := ipv4.NewPacketConn(udpConn)
+		if source != nil {
+			err = p.JoinSourceSpecificGroup(ifi, groupAddr, &net.UDPAddr{IP: source})
+		} else {
+			err = p.JoinGroup(ifi, groupAddr)
+		}
+		if err == nil && ifi != nil {
+			err = p.SetMulticastInterface(ifi)
+		}
+		if err == nil && ttl != 0 {
+			err = p.SetMulticastTTL(ttl)
+		}
+	} else {
+		p := ipv6.NewPacketConn(udpConn)
+		if source != nil {
+			err = p.JoinSourceSpecificGroup(ifi, groupAddr, &net.UDPAddr{IP: source})
+		} else {
+			err = p.JoinGroup(ifi, groupAddr)
+		}
+		if err == nil && ifi != nil {
+			err = p.SetMulticastInterface(ifi)
+		}
+		if err == nil && ttl != 0 {
+			err = p.SetMulticastHopLimit(ttl)
+		}
+	}
+
+	if err != nil {
+		udpConn.Close()
+		return nil, fmt.Errorf("join multicast group %s: %w", group, err)
+	}
+
+	return udpConn, nil
+}
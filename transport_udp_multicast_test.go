@@ -0,0 +1,240 @@
+package rtsp
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/ipv4"
+)
+
+func testLoopbackInterface() *net.Interface {
+	list, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+
+	for i := range list {
+		ifi := &list[i]
+		if ifi.Flags&net.FlagLoopback != 0 && ifi.Flags&net.FlagUp != 0 {
+			return ifi
+		}
+	}
+
+	return nil
+}
+
+func TestTransportUDPMulticast_Configure(t *testing.T) {
+	assert := assert.New(t)
+
+	tr := NewTransportUDPMulticast(nil)
+	defer tr.Close()
+
+	value, err := tr.Setup(0)
+	assert.NoError(err)
+	assert.Equal("RTP/AVP;multicast", value)
+
+	for _, value := range []string{
+		"RTP/AVP;unicast;server_port=5000-5001",
+		"RTP/AVP;multicast;destination=10.0.0.1;port=5000-5001",
+		"RTP/AVP;multicast;destination=239.255.0.1",
+	} {
+		transport, err := ParseTransportHeader(value)
+		if assert.NoError(err) {
+			assert.Error(tr.Configure(0, transport), value)
+		}
+	}
+}
+
+func TestTransportUDPMulticast_Play(t *testing.T) {
+	assert := assert.New(t)
+
+	ifi := testLoopbackInterface()
+	if ifi == nil {
+		t.Skip("loopback interface is not available")
+	}
+
+	// find free port pair
+	probe, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if !assert.NoError(err) {
+		return
+	}
+	port := probe.LocalAddr().(*net.UDPAddr).Port &^ 1
+	probe.Close()
+
+	group := net.IPv4(239, 255, 0, 1)
+
+	tr := NewTransportUDPMulticast(ifi)
+	defer tr.Close()
+
+	err = tr.Configure(0, &TransportHeader{
+		Profile:     "RTP/AVP",
+		Multicast:   true,
+		Destination: group,
+		Source:      net.IPv4(127, 0, 0, 1),
+		TTL:         1,
+		Port:        [2]int{port, port + 1},
+	})
+	if err != nil {
+		t.Skipf("multicast is not available: %v", err)
+	}
+
+	// second receiver on the same host
+	tr2 := NewTransportUDPMulticast(ifi)
+	defer tr2.Close()
+
+	err = tr2.Configure(0, &TransportHeader{
+		Profile:     "RTP/AVP",
+		Multicast:   true,
+		Destination: group,
+		Source:      net.IPv4(127, 0, 0, 1),
+		TTL:         1,
+		Port:        [2]int{port, port + 1},
+	})
+	if !assert.NoError(err) {
+		return
+	}
+
+	handler := &testClientHandler{
+		packets: make(chan []byte, 1),
+	}
+	tr.Play(handler)
+
+	handler2 := &testClientHandler{
+		packets: make(chan []byte, 1),
+	}
+	tr2.Play(handler2)
+
+	sender, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if !assert.NoError(err) {
+		return
+	}
+	defer sender.Close()
+
+	p := ipv4.NewPacketConn(sender)
+	if err := p.SetMulticastInterface(ifi); err != nil {
+		t.Skipf("multicast is not available: %v", err)
+	}
+	p.SetMulticastLoopback(true)
+
+	packet := []byte{0x80, 0x60, 0x00, 0x01}
+	addr := &net.UDPAddr{IP: group, Port: port}
+
+	var received, received2 []byte
+
+	timeout := time.After(time.Second)
+	for received == nil || received2 == nil {
+		if _, err := sender.WriteToUDP(packet, addr); err != nil {
+			t.Skipf("multicast is not available: %v", err)
+		}
+
+		select {
+		case received = <-handler.packets:
+		case received2 = <-handler2.packets:
+		case <-timeout:
+			t.Skip("multicast is not delivered")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	assert.Equal(packet, received)
+	assert.Equal(packet, received2)
+}
+
+func TestTransportUDPMulticast_Groups(t *testing.T) {
+	assert := assert.New(t)
+
+	ifi := testLoopbackInterface()
+	if ifi == nil {
+		t.Skip("loopback interface is not available")
+	}
+
+	// find free port pair
+	probe, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if !assert.NoError(err) {
+		return
+	}
+	port := probe.LocalAddr().(*net.UDPAddr).Port &^ 1
+	probe.Close()
+
+	group := net.IPv4(239, 255, 0, 1)
+	group2 := net.IPv4(239, 255, 0, 2)
+
+	// receivers of different groups on the same port
+	tr := NewTransportUDPMulticast(ifi)
+	defer tr.Close()
+
+	err = tr.Configure(0, &TransportHeader{
+		Profile:     "RTP/AVP",
+		Multicast:   true,
+		Destination: group,
+		TTL:         1,
+		Port:        [2]int{port, port + 1},
+	})
+	if err != nil {
+		t.Skipf("multicast is not available: %v", err)
+	}
+
+	tr2 := NewTransportUDPMulticast(ifi)
+	defer tr2.Close()
+
+	err = tr2.Configure(0, &TransportHeader{
+		Profile:     "RTP/AVP",
+		Multicast:   true,
+		Destination: group2,
+		TTL:         1,
+		Port:        [2]int{port, port + 1},
+	})
+	if !assert.NoError(err) {
+		return
+	}
+
+	handler := &testClientHandler{
+		packets: make(chan []byte, 1),
+	}
+	tr.Play(handler)
+
+	handler2 := &testClientHandler{
+		packets: make(chan []byte, 1),
+	}
+	tr2.Play(handler2)
+
+	sender, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if !assert.NoError(err) {
+		return
+	}
+	defer sender.Close()
+
+	p := ipv4.NewPacketConn(sender)
+	if err := p.SetMulticastInterface(ifi); err != nil {
+		t.Skipf("multicast is not available: %v", err)
+	}
+	p.SetMulticastLoopback(true)
+
+	packet := []byte{0x80, 0x60, 0x00, 0x01}
+	addr := &net.UDPAddr{IP: group, Port: port}
+
+	timeout := time.After(time.Second)
+	for received := false; !received; {
+		if _, err := sender.WriteToUDP(packet, addr); err != nil {
+			t.Skipf("multicast is not available: %v", err)
+		}
+
+		select {
+		case data := <-handler.packets:
+			assert.Equal(packet, data)
+			received = true
+		case <-timeout:
+			t.Skip("multicast is not delivered")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	// packets of the first group are not delivered to the second receiver
+	select {
+	case <-handler2.packets:
+		assert.Fail("packet of other group")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,306 @@
+package rtsp
+
+import (
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+func TestTransportUDP_Filter(t *testing.T) {
+	localhost := net.IPv4(127, 0, 0, 1)
+
+	valid := []byte{0x80, 0x60, 0x00, 0x01, 0, 0, 0, 0, 0x1A, 0x2B, 0x3C, 0x4D}
+	otherSSRC := []byte{0x80, 0x60, 0x00, 0x02, 0, 0, 0, 0, 0x00, 0x00, 0x00, 0x01}
+	stray := []byte{0x80, 0x60, 0x00, 0x03, 0, 0, 0, 0, 0x1A, 0x2B, 0x3C, 0x4D}
+
+	tests := []struct {
+		name     string
+		config   UDPConfig
+		expected [][]byte
+		stats    TransportStats
+	}{
+		{
+			name:     "default",
+			expected: [][]byte{otherSSRC, valid},
+			stats:    TransportStats{RTPPackets: 2, Dropped: 1},
+		},
+		{
+			name:     "filter ssrc",
+			config:   UDPConfig{FilterSSRC: true},
+			expected: [][]byte{valid},
+			stats:    TransportStats{RTPPackets: 1, Dropped: 2},
+		},
+		{
+			name:     "any source",
+			config:   UDPConfig{AnySource: true},
+			expected: [][]byte{stray, otherSSRC, valid},
+			stats:    TransportStats{RTPPackets: 3},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			server, err := net.ListenUDP("udp", &net.UDPAddr{IP: localhost})
+			if !assert.NoError(err) {
+				return
+			}
+			defer server.Close()
+
+			straySender, err := net.ListenUDP("udp", &net.UDPAddr{IP: localhost})
+			if !assert.NoError(err) {
+				return
+			}
+			defer straySender.Close()
+
+			tr := NewTransportUDPWithConfig(localhost, test.config)
+			defer tr.Close()
+
+			value, err := tr.Setup(0)
+			if !assert.NoError(err) {
+				return
+			}
+
+			request, err := ParseTransportHeader(value)
+			if !assert.NoError(err) {
+				return
+			}
+
+			serverPort := server.LocalAddr().(*net.UDPAddr).Port
+			err = tr.Configure(0, &TransportHeader{
+				Profile:    "RTP/AVP",
+				ClientPort: request.ClientPort,
+				ServerPort: [2]int{serverPort, serverPort + 1},
+				SSRC:       0x1A2B3C4D,
+				HasSSRC:    true,
+			})
+			if !assert.NoError(err) {
+				return
+			}
+
+			handler := &testClientHandler{
+				packets: make(chan []byte, 4),
+			}
+			tr.Play(handler)
+
+			client := &net.UDPAddr{IP: localhost, Port: request.ClientPort[0]}
+
+			// unexpected sender
+			straySender.WriteToUDP(stray, client)
+			// unexpected SSRC
+			server.WriteToUDP(otherSSRC, client)
+			server.WriteToUDP(valid, client)
+
+			var received [][]byte
+			for len(received) < len(test.expected) {
+				select {
+				case packet := <-handler.packets:
+					received = append(received, packet)
+				case <-time.After(time.Second):
+					assert.Fail("timeout")
+					return
+				}
+			}
+
+			assert.ElementsMatch(test.expected, received)
+			assert.Equal(test.stats, tr.Stats())
+		})
+	}
+}
+
+func TestTransportUDP_HolePunch(t *testing.T) {
+	assert := assert.New(t)
+
+	localhost := net.IPv4(127, 0, 0, 1)
+
+	serverRTP, err := net.ListenUDP("udp", &net.UDPAddr{IP: localhost})
+	if !assert.NoError(err) {
+		return
+	}
+	defer serverRTP.Close()
+
+	serverRTCP, err := net.ListenUDP("udp", &net.UDPAddr{IP: localhost})
+	if !assert.NoError(err) {
+		return
+	}
+	defer serverRTCP.Close()
+
+	tr := NewTransportUDPWithConfig(localhost, UDPConfig{})
+	tr.HolePunchSchedule = []time.Duration{0, 10 * time.Millisecond}
+	defer tr.Close()
+
+	value, err := tr.Setup(0)
+	if !assert.NoError(err) {
+		return
+	}
+
+	request, err := ParseTransportHeader(value)
+	if !assert.NoError(err) {
+		return
+	}
+
+	err = tr.Configure(0, &TransportHeader{
+		Profile:    "RTP/AVP",
+		ClientPort: request.ClientPort,
+		ServerPort: [2]int{
+			serverRTP.LocalAddr().(*net.UDPAddr).Port,
+			serverRTCP.LocalAddr().(*net.UDPAddr).Port,
+		},
+	})
+	if !assert.NoError(err) {
+		return
+	}
+
+	buf := make([]byte, 64)
+
+	for i := 0; i < 2; i++ {
+		serverRTP.SetReadDeadline(time.Now().Add(time.Second))
+		n, addr, err := serverRTP.ReadFromUDP(buf)
+		if !assert.NoError(err) {
+			return
+		}
+		assert.Equal(holePunchRTP, buf[:n])
+		assert.Equal(request.ClientPort[0], addr.Port)
+
+		serverRTCP.SetReadDeadline(time.Now().Add(time.Second))
+		n, addr, err = serverRTCP.ReadFromUDP(buf)
+		if !assert.NoError(err) {
+			return
+		}
+		assert.Equal(holePunchRTCP, buf[:n])
+		assert.Equal(request.ClientPort[1], addr.Port)
+	}
+}
+
+func TestTransportUDP_Config(t *testing.T) {
+	assert := assert.New(t)
+
+	localhost := net.IPv4(127, 0, 0, 1)
+
+	// find free port
+	probe, err := net.ListenUDP("udp", &net.UDPAddr{IP: localhost})
+	if !assert.NoError(err) {
+		return
+	}
+	port := probe.LocalAddr().(*net.UDPAddr).Port &^ 1
+	probe.Close()
+
+	config := UDPConfig{
+		MinPort:    port,
+		MaxPort:    port + 1,
+		LocalIP:    localhost,
+		ReadBuffer: 0x40000,
+		DSCP:       46,
+	}
+
+	tr := NewTransportUDPWithConfig(localhost, config)
+	defer tr.Close()
+
+	value, err := tr.Setup(0)
+	if !assert.NoError(err) {
+		return
+	}
+	assert.Equal(fmt.Sprintf("RTP/AVP;unicast;client_port=%d-%d", port, port+1), value)
+
+	c := tr.getConn(0)
+	if assert.NotNil(c) {
+		addr := c.rtpConn.LocalAddr().(*net.UDPAddr)
+		assert.True(localhost.Equal(addr.IP))
+
+		if tos, err := ipv4.NewConn(c.rtpConn).TOS(); err == nil {
+			assert.Equal(46<<2, tos)
+		}
+	}
+
+	// range is exhausted
+	tr2 := NewTransportUDPWithConfig(localhost, config)
+	defer tr2.Close()
+
+	_, err = tr2.Setup(0)
+	assert.ErrorIs(err, ErrNoFreePorts)
+
+	tr2.Config = UDPConfig{MinPort: 6000, MaxPort: 6000}
+	_, err = tr2.Setup(0)
+	assert.Error(err)
+	assert.NotErrorIs(err, ErrNoFreePorts)
+}
+
+func TestTransportUDP_DSCP(t *testing.T) {
+	assert := assert.New(t)
+
+	// default bind address
+	tr := NewTransportUDPWithConfig(nil, UDPConfig{DSCP: 46})
+	defer tr.Close()
+
+	if _, err := tr.Setup(0); !assert.NoError(err) {
+		return
+	}
+
+	c := tr.getConn(0)
+	if !assert.NotNil(c) {
+		return
+	}
+
+	// dual-stack socket marks both IPv4 and IPv6 packets
+	if addr := c.rtpConn.LocalAddr().(*net.UDPAddr); addr.IP.To4() == nil {
+		if tclass, err := ipv6.NewConn(c.rtpConn).TrafficClass(); err == nil {
+			assert.Equal(46<<2, tclass)
+		}
+	}
+
+	if tos, err := ipv4.NewConn(c.rtpConn).TOS(); err == nil {
+		assert.Equal(46<<2, tos)
+	}
+
+	_, err := NewTransportUDPWithConfig(nil, UDPConfig{DSCP: 64}).Setup(0)
+	assert.Error(err)
+}
+
+func TestTransportUDP_PortScan(t *testing.T) {
+	assert := assert.New(t)
+
+	localhost := net.IPv4(127, 0, 0, 1)
+
+	// find free port
+	probe, err := net.ListenUDP("udp", &net.UDPAddr{IP: localhost})
+	if !assert.NoError(err) {
+		return
+	}
+	port := probe.LocalAddr().(*net.UDPAddr).Port &^ 1
+	probe.Close()
+
+	// two of three pairs are in use
+	for _, p := range []int{port, port + 2} {
+		busy, err := net.ListenUDP("udp", &net.UDPAddr{IP: localhost, Port: p})
+		if err != nil {
+			t.Skipf("port %d is not available: %v", p, err)
+		}
+		defer busy.Close()
+	}
+
+	config := UDPConfig{
+		MinPort: port,
+		MaxPort: port + 5,
+		LocalIP: localhost,
+	}
+
+	// free pair is found from any random offset
+	for i := 0; i < 10; i++ {
+		tr := NewTransportUDPWithConfig(localhost, config)
+
+		value, err := tr.Setup(0)
+		if assert.NoError(err) {
+			assert.Equal(fmt.Sprintf("RTP/AVP;unicast;client_port=%d-%d", port+4, port+5), value)
+		}
+
+		tr.Close()
+	}
+}
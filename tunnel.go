@@ -0,0 +1,213 @@
+package rtsp
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// TunnelMimeType is the content type of the RTSP-over-HTTP tunnel channels
+const TunnelMimeType = "application/x-rtsp-tunnelled"
+
+// Default HTTP ports for the tunnel if URL port is not defined
+const (
+	defaultTunnelPort    = "80"
+	defaultTunnelTLSPort = "443"
+)
+
+// tunnelConn is the RTSP-over-HTTP tunnel.
+// Server messages are received on the GET channel,
+// client messages are sent base64-encoded on the POST channel
+type tunnelConn struct {
+	get    net.Conn
+	post   net.Conn
+	reader *bufio.Reader
+}
+
+func newSessionCookie() (string, error) {
+	buf := make([]byte, 11)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// tunnelURL returns URL to connect the tunnel channels.
+// If URL port is not defined, HTTP port is used: 80 or 443 for rtsps:// scheme
+func tunnelURL(u *url.URL) *url.URL {
+	if u.Port() != "" {
+		return u
+	}
+
+	port := defaultTunnelPort
+	if u.Scheme == "rtsps" {
+		port = defaultTunnelTLSPort
+	}
+
+	t := *u
+	t.Host = net.JoinHostPort(u.Hostname(), port)
+
+	return &t
+}
+
+// dialTunnel opens GET and POST channels linked with the x-sessioncookie.
+// Channels are connected to the URL host with TLS for rtsps:// scheme.
+// URL without port is connected to the HTTP port
+func dialTunnel(
+	ctx context.Context,
+	dialer *net.Dialer,
+	u *url.URL,
+	config *tls.Config,
+	userAgent string,
+) (net.Conn, error) {
+	cookie, err := newSessionCookie()
+	if err != nil {
+		return nil, err
+	}
+
+	target := tunnelURL(u)
+
+	get, err := dial(ctx, dialer, target, config)
+	if err != nil {
+		return nil, err
+	}
+
+	if dialer.Timeout > 0 {
+		get.SetDeadline(time.Now().Add(dialer.Timeout))
+	}
+
+	reader, err := openTunnelGet(get, u, cookie, userAgent)
+	if err != nil {
+		get.Close()
+		return nil, err
+	}
+
+	get.SetDeadline(time.Time{})
+
+	post, err := dial(ctx, dialer, target, config)
+	if err != nil {
+		get.Close()
+		return nil, err
+	}
+
+	if err := openTunnelPost(post, u, cookie, userAgent); err != nil {
+		get.Close()
+		post.Close()
+		return nil, err
+	}
+
+	return &tunnelConn{
+		get:    get,
+		post:   post,
+		reader: reader,
+	}, nil
+}
+
+// openTunnelGet sends GET request and reads the response.
+// Returns reader for the server messages
+func openTunnelGet(conn net.Conn, u *url.URL, cookie, userAgent string) (*bufio.Reader, error) {
+	bw := bufio.NewWriter(conn)
+	writeTunnelHeader(bw, http.MethodGet, u, cookie, userAgent)
+	bw.WriteString("Accept: " + TunnelMimeType + "\r\n")
+	bw.WriteString("\r\n")
+
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	response, err := http.ReadResponse(br, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("tunnel: %s", response.Status)
+	}
+
+	return br, nil
+}
+
+// openTunnelPost sends POST request headers.
+// Request body is never finished, server does not send a response
+func openTunnelPost(conn net.Conn, u *url.URL, cookie, userAgent string) error {
+	bw := bufio.NewWriter(conn)
+	writeTunnelHeader(bw, http.MethodPost, u, cookie, userAgent)
+	bw.WriteString("Content-Type: " + TunnelMimeType + "\r\n")
+	bw.WriteString("Content-Length: 32767\r\n")
+	bw.WriteString("Expires: Sun, 9 Jan 1972 00:00:00 GMT\r\n")
+	bw.WriteString("\r\n")
+
+	return bw.Flush()
+}
+
+func writeTunnelHeader(bw *bufio.Writer, method string, u *url.URL, cookie, userAgent string) {
+	bw.WriteString(method + " " + u.RequestURI() + " HTTP/1.0\r\n")
+	bw.WriteString("Host: " + u.Host + "\r\n")
+	if userAgent != "" {
+		bw.WriteString("User-Agent: " + userAgent + "\r\n")
+	}
+	bw.WriteString("x-sessioncookie: " + cookie + "\r\n")
+	bw.WriteString("Pragma: no-cache\r\n")
+	bw.WriteString("Cache-Control: no-cache\r\n")
+}
+
+func (t *tunnelConn) Read(b []byte) (int, error) {
+	return t.reader.Read(b)
+}
+
+// Write sends base64-encoded data to the POST channel.
+// Each chunk is encoded with padding,
+// server decodes every 4 characters independently
+func (t *tunnelConn) Write(b []byte) (int, error) {
+	buf := make([]byte, base64.StdEncoding.EncodedLen(len(b)))
+	base64.StdEncoding.Encode(buf, b)
+
+	if _, err := t.post.Write(buf); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+func (t *tunnelConn) Close() error {
+	err := t.get.Close()
+	if e := t.post.Close(); err == nil {
+		err = e
+	}
+
+	return err
+}
+
+func (t *tunnelConn) LocalAddr() net.Addr {
+	return t.get.LocalAddr()
+}
+
+func (t *tunnelConn) RemoteAddr() net.Addr {
+	return t.get.RemoteAddr()
+}
+
+func (t *tunnelConn) SetDeadline(deadline time.Time) error {
+	if err := t.get.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	return t.post.SetDeadline(deadline)
+}
+
+func (t *tunnelConn) SetReadDeadline(deadline time.Time) error {
+	return t.get.SetReadDeadline(deadline)
+}
+
+func (t *tunnelConn) SetWriteDeadline(deadline time.Time) error {
+	return t.post.SetWriteDeadline(deadline)
+}
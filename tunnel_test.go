@@ -0,0 +1,215 @@
+package rtsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testTunnelListener accepts GET and POST channels
+// and links them into the RTSP connection for the Server
+type testTunnelListener struct {
+	net.Listener
+
+	lock    sync.Mutex
+	pending map[string]net.Conn
+	cookies chan string
+	conns   chan net.Conn
+	done    chan struct{}
+}
+
+type testTunnelConn struct {
+	net.Conn
+	reader io.Reader
+}
+
+func (c *testTunnelConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+func (l *testTunnelListener) Accept() (net.Conn, error) {
+	select {
+	case c := <-l.conns:
+		return c, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *testTunnelListener) loop() {
+	defer close(l.done)
+
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return
+		}
+
+		go l.handle(conn)
+	}
+}
+
+func (l *testTunnelListener) handle(conn net.Conn) {
+	br := bufio.NewReader(conn)
+	request, err := http.ReadRequest(br)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	cookie := request.Header.Get("x-sessioncookie")
+
+	if request.Method == http.MethodGet {
+		conn.Write([]byte("HTTP/1.0 200 OK\r\nContent-Type: " + TunnelMimeType + "\r\n\r\n"))
+
+		l.lock.Lock()
+		l.pending[cookie] = conn
+		l.lock.Unlock()
+
+		return
+	}
+
+	l.lock.Lock()
+	get := l.pending[cookie]
+	delete(l.pending, cookie)
+	l.lock.Unlock()
+
+	if get == nil {
+		conn.Close()
+		return
+	}
+
+	l.cookies <- cookie
+
+	pr, pw := io.Pipe()
+	go func() {
+		defer conn.Close()
+
+		// decode every 4 characters independently
+		var group [4]byte
+		buf := make([]byte, 3)
+		for {
+			if _, err := io.ReadFull(br, group[:]); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+
+			n, err := base64.StdEncoding.Decode(buf, group[:])
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+
+			if _, err := pw.Write(buf[:n]); err != nil {
+				return
+			}
+		}
+	}()
+
+	l.conns <- &testTunnelConn{Conn: get, reader: pr}
+}
+
+func startTestTunnelServer(handler ServerHandler) (*Server, string, *testTunnelListener) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, "", nil
+	}
+
+	l := &testTunnelListener{
+		Listener: listener,
+		pending:  make(map[string]net.Conn),
+		cookies:  make(chan string, 1),
+		conns:    make(chan net.Conn, 1),
+		done:     make(chan struct{}),
+	}
+	go l.loop()
+
+	s := &Server{Handler: handler}
+	go s.Serve(l)
+
+	return s, listener.Addr().String(), l
+}
+
+func TestTunnel_tunnelURL(t *testing.T) {
+	assert := assert.New(t)
+
+	u, _ := url.Parse("rtsp://camera.local/live")
+	assert.Equal("camera.local:80", tunnelURL(u).Host)
+	assert.Equal("camera.local", u.Host)
+
+	u, _ = url.Parse("rtsps://camera.local/live")
+	assert.Equal("camera.local:443", tunnelURL(u).Host)
+
+	u, _ = url.Parse("rtsp://[::1]:8080/live")
+	assert.Equal("[::1]:8080", tunnelURL(u).Host)
+}
+
+func TestTunnel_Play(t *testing.T) {
+	assert := assert.New(t)
+
+	h := &testServerHandler{
+		items: []*SdpItem{
+			{
+				Type:   "video",
+				Format: 96,
+				Media:  NewMediaH264(90000),
+			},
+		},
+		teardown: make(chan *ServerSession, 1),
+	}
+
+	s, addr, l := startTestTunnelServer(h)
+	defer s.Close()
+
+	u, _ := url.Parse("rtsp://" + addr + "/live")
+	c := &Client{
+		URL:        u,
+		HTTPTunnel: true,
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if !assert.NoError(c.Start(ctx)) {
+		return
+	}
+	assert.Len(<-l.cookies, 22)
+
+	sdp := c.GetSDP()
+	if !assert.Len(sdp, 1) {
+		return
+	}
+
+	if !assert.NoError(c.Setup(ctx, 0, sdp[0].URL)) {
+		return
+	}
+
+	handler := &testClientHandler{
+		packets: make(chan []byte, 1),
+	}
+
+	playErrorCh := make(chan error, 1)
+	go func() {
+		playErrorCh <- c.Play(ctx, handler)
+	}()
+
+	select {
+	case packet := <-handler.packets:
+		assert.Equal([]byte{0x80, 0x60, 0x00, 0x01}, packet)
+	case <-time.After(time.Second):
+		assert.Fail("timeout")
+	}
+
+	cancel()
+	assert.NoError(<-playErrorCh)
+}